@@ -0,0 +1,381 @@
+package main
+
+import (
+	"context"
+	"crypto"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"math/big"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Authentication configuration - off by default
+var (
+	authMode        = getEnv("AUTH_MODE", "none") // "none", "bearer" or "jwt"
+	authBearerToken = getEnv("AUTH_BEARER_TOKEN", "")
+	authJWTIssuer   = getEnv("AUTH_JWT_ISSUER", "")
+	authJWTAudience = getEnv("AUTH_JWT_AUDIENCE", "") // required in jwt mode
+	authJWTJWKSURL  = getEnv("AUTH_JWT_JWKS_URL", "")
+
+	// JWT claim that identifies the principal recorded on sessions
+	authPrincipalClaim = getEnv("AUTH_PRINCIPAL_CLAIM", "sub")
+
+	// Bearer token for the /admin endpoints, which change routing state and expose live session IDs; they are off without it
+	adminToken = getEnv("ADMIN_TOKEN", "")
+)
+
+// principalKey is the context key for the authenticated principal of a request
+type principalKey struct{}
+
+// principalFromContext returns the authenticated principal, or an empty string when unauthenticated
+func principalFromContext(ctx context.Context) string {
+	principal, _ := ctx.Value(principalKey{}).(string)
+	return principal
+}
+
+// Authenticator validates the credentials on an incoming request and returns the authenticated principal
+type Authenticator interface {
+	Authenticate(r *http.Request) (string, error)
+}
+
+// newAuthenticator builds the configured authenticator, or nil when authentication is disabled
+func newAuthenticator() (Authenticator, error) {
+	switch strings.ToLower(authMode) {
+	case "", "none":
+		return nil, nil
+	case "bearer":
+		if authBearerToken == "" {
+			return nil, errors.New("AUTH_BEARER_TOKEN is required for bearer authentication")
+		}
+		return &bearerAuthenticator{token: authBearerToken}, nil
+	case "jwt":
+		if authJWTJWKSURL == "" {
+			return nil, errors.New("AUTH_JWT_JWKS_URL is required for JWT authentication")
+		}
+		if authJWTAudience == "" {
+			return nil, errors.New("AUTH_JWT_AUDIENCE is required for JWT authentication")
+		}
+		return &jwtAuthenticator{
+			issuer:         authJWTIssuer,
+			audience:       authJWTAudience,
+			principalClaim: authPrincipalClaim,
+			jwks:           &jwksCache{url: authJWTJWKSURL, keys: make(map[string]*rsa.PublicKey)},
+		}, nil
+	default:
+		return nil, fmt.Errorf("unknown AUTH_MODE %q", authMode)
+	}
+}
+
+// authMiddleware rejects requests without valid credentials before they reach the MCP handler
+func authMiddleware(auth Authenticator, next http.Handler) http.Handler {
+	if auth == nil {
+		return next
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		principal, err := auth.Authenticate(r)
+		if err != nil {
+			log.Printf("🔒 Rejecting unauthenticated request %s %s: %v", r.Method, r.URL.Path, err)
+			w.Header().Set("WWW-Authenticate", `Bearer realm="mcp-helper"`)
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		log.Printf("🔓 Authenticated request from %s", principal)
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), principalKey{}, principal)))
+	})
+}
+
+// registerAdminRoutes mounts the /admin endpoints behind the admin authenticator
+func registerAdminRoutes(mux *http.ServeMux, helper *MCPHelper, admin Authenticator) {
+	// Fresh initialize + tools/list against a backend, for diagnosing connectivity
+	mux.Handle("/admin/probe", authMiddleware(admin, http.HandlerFunc(helper.handleProbe)))
+
+	// Put backends into or out of maintenance, and list those in maintenance
+	mux.Handle("/admin/maintenance", authMiddleware(admin, http.HandlerFunc(helper.handleMaintenance)))
+
+	// Re-list every backend's tools now, or one backend's with ?backend=<name>, notifying clients of any change
+	mux.Handle("/admin/refresh", authMiddleware(admin, http.HandlerFunc(helper.handleAdminRefresh)))
+
+	// Active sessions and their backend mappings
+	mux.Handle("/admin/sessions", authMiddleware(admin, http.HandlerFunc(helper.handleAdminSessions)))
+	mux.Handle("/admin/sessions/{id}", authMiddleware(admin, http.HandlerFunc(helper.handleAdminSession)))
+}
+
+// extractBearerToken returns the token from an "Authorization: Bearer <token>" header
+func extractBearerToken(r *http.Request) (string, error) {
+	header := r.Header.Get("Authorization")
+	if header == "" {
+		return "", errors.New("missing Authorization header")
+	}
+	scheme, token, ok := strings.Cut(header, " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") || token == "" {
+		return "", errors.New("authorization header is not a bearer token")
+	}
+	return strings.TrimSpace(token), nil
+}
+
+// bearerPrincipal is the principal of every request authenticated by the shared bearer token. One token
+// can't tell callers apart, so per-principal rate limits and session ownership treat all bearer clients as
+// a single caller; use jwt mode to tell them apart.
+const bearerPrincipal = "bearer"
+
+// bearerAuthenticator accepts a single static bearer token
+type bearerAuthenticator struct {
+	token string
+}
+
+func (a *bearerAuthenticator) Authenticate(r *http.Request) (string, error) {
+	token, err := extractBearerToken(r)
+	if err != nil {
+		return "", err
+	}
+	if subtle.ConstantTimeCompare([]byte(token), []byte(a.token)) != 1 {
+		return "", errors.New("invalid bearer token")
+	}
+	return bearerPrincipal, nil
+}
+
+// jwtAuthenticator validates RS256 JWTs against a JWKS endpoint, issuer and audience
+type jwtAuthenticator struct {
+	issuer         string
+	audience       string
+	principalClaim string // claim identifying the principal, e.g. "sub"
+	jwks           *jwksCache
+}
+
+// jwtClaims holds the registered claims the helper checks
+type jwtClaims struct {
+	Issuer    string          `json:"iss"`
+	Audience  json.RawMessage `json:"aud"`
+	ExpiresAt *int64          `json:"exp"`
+	NotBefore *int64          `json:"nbf"`
+}
+
+func (a *jwtAuthenticator) Authenticate(r *http.Request) (string, error) {
+	token, err := extractBearerToken(r)
+	if err != nil {
+		return "", err
+	}
+
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return "", errors.New("malformed JWT")
+	}
+
+	var header struct {
+		Alg string `json:"alg"`
+		Kid string `json:"kid"`
+	}
+	if err := decodeJWTSegment(parts[0], &header); err != nil {
+		return "", fmt.Errorf("invalid JWT header: %w", err)
+	}
+	if header.Alg != "RS256" {
+		return "", fmt.Errorf("unsupported JWT algorithm %q", header.Alg)
+	}
+
+	key, err := a.jwks.key(r.Context(), header.Kid)
+	if err != nil {
+		return "", err
+	}
+
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return "", fmt.Errorf("invalid JWT signature encoding: %w", err)
+	}
+	digest := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+	if err := rsa.VerifyPKCS1v15(key, crypto.SHA256, digest[:], signature); err != nil {
+		return "", errors.New("invalid JWT signature")
+	}
+
+	var claims jwtClaims
+	if err := decodeJWTSegment(parts[1], &claims); err != nil {
+		return "", fmt.Errorf("invalid JWT claims: %w", err)
+	}
+	if err := a.validateClaims(claims); err != nil {
+		return "", err
+	}
+
+	return a.extractPrincipal(parts[1])
+}
+
+// extractPrincipal returns the configured principal claim from the JWT claims segment
+func (a *jwtAuthenticator) extractPrincipal(segment string) (string, error) {
+	var allClaims map[string]any
+	if err := decodeJWTSegment(segment, &allClaims); err != nil {
+		return "", fmt.Errorf("invalid JWT claims: %w", err)
+	}
+
+	value, ok := allClaims[a.principalClaim]
+	if !ok {
+		return "", fmt.Errorf("JWT has no %q claim", a.principalClaim)
+	}
+	principal, ok := value.(string)
+	if !ok || principal == "" {
+		return "", fmt.Errorf("JWT claim %q is not a non-empty string", a.principalClaim)
+	}
+	return principal, nil
+}
+
+// validateClaims checks expiry, not-before, issuer and audience
+func (a *jwtAuthenticator) validateClaims(claims jwtClaims) error {
+	now := time.Now().Unix()
+	if claims.ExpiresAt == nil || now >= *claims.ExpiresAt {
+		return errors.New("JWT is expired or has no expiry")
+	}
+	if claims.NotBefore != nil && now < *claims.NotBefore {
+		return errors.New("JWT is not valid yet")
+	}
+	if a.issuer != "" && claims.Issuer != a.issuer {
+		return fmt.Errorf("unexpected JWT issuer %q", claims.Issuer)
+	}
+	if !audienceContains(claims.Audience, a.audience) {
+		return errors.New("JWT audience does not match")
+	}
+	return nil
+}
+
+// audienceContains reports whether an aud claim (string or array) contains the audience
+func audienceContains(raw json.RawMessage, audience string) bool {
+	var single string
+	if err := json.Unmarshal(raw, &single); err == nil {
+		return single == audience
+	}
+	var multiple []string
+	if err := json.Unmarshal(raw, &multiple); err == nil {
+		for _, aud := range multiple {
+			if aud == audience {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// decodeJWTSegment decodes a base64url JSON segment of a JWT
+func decodeJWTSegment(segment string, v any) error {
+	data, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
+
+// jwksCache fetches and caches RSA signing keys from a JWKS endpoint
+type jwksCache struct {
+	url       string
+	keys      map[string]*rsa.PublicKey
+	fetchedAt time.Time
+	lock      sync.Mutex
+	fetches   singleFlight
+}
+
+// Refresh the key set periodically, and at most this often when an unknown key ID shows up
+const (
+	jwksRefreshInterval = 5 * time.Minute
+	jwksMinRefetch      = 30 * time.Second
+)
+
+// key returns the signing key for a key ID, refreshing the key set when needed
+func (c *jwksCache) key(ctx context.Context, kid string) (*rsa.PublicKey, error) {
+	c.lock.Lock()
+	key, found := c.keys[kid]
+	stale := time.Since(c.fetchedAt) > jwksRefreshInterval
+	refetch := stale || time.Since(c.fetchedAt) > jwksMinRefetch
+	c.lock.Unlock()
+
+	if found && !stale {
+		return key, nil
+	}
+
+	if refetch {
+		// Concurrent misses share one fetch, and the lock is not held while it runs so
+		// requests with known keys are not stalled behind a slow JWKS endpoint. The fetch is detached
+		// from the caller that starts it, so its cancellation doesn't fail every caller waiting on it.
+		fetchCtx := context.WithoutCancel(ctx)
+		if _, err := c.fetches.do(c.url, func() error { return c.refresh(fetchCtx) }); err != nil {
+			if found {
+				log.Printf("⚠️ JWKS refresh failed, using cached key %s: %v", kid, err)
+				return key, nil
+			}
+			return nil, err
+		}
+	}
+
+	c.lock.Lock()
+	key, found = c.keys[kid]
+	c.lock.Unlock()
+	if !found {
+		return nil, fmt.Errorf("unknown JWT key ID %q", kid)
+	}
+	return key, nil
+}
+
+// refresh fetches the key set, within its own timeout, and swaps it in under the lock
+func (c *jwksCache) refresh(ctx context.Context) error {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.url, nil)
+	if err != nil {
+		return fmt.Errorf("failed to build JWKS request: %w", err)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to fetch JWKS: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("failed to fetch JWKS: status %d", resp.StatusCode)
+	}
+
+	var jwks struct {
+		Keys []struct {
+			Kid string `json:"kid"`
+			Kty string `json:"kty"`
+			N   string `json:"n"`
+			E   string `json:"e"`
+		} `json:"keys"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&jwks); err != nil {
+		return fmt.Errorf("failed to decode JWKS: %w", err)
+	}
+
+	keys := make(map[string]*rsa.PublicKey)
+	for _, jwk := range jwks.Keys {
+		if jwk.Kty != "RSA" {
+			continue
+		}
+		n, err := base64.RawURLEncoding.DecodeString(jwk.N)
+		if err != nil {
+			log.Printf("⚠️ Skipping JWKS key %s with invalid modulus: %v", jwk.Kid, err)
+			continue
+		}
+		e, err := base64.RawURLEncoding.DecodeString(jwk.E)
+		if err != nil {
+			log.Printf("⚠️ Skipping JWKS key %s with invalid exponent: %v", jwk.Kid, err)
+			continue
+		}
+		keys[jwk.Kid] = &rsa.PublicKey{
+			N: new(big.Int).SetBytes(n),
+			E: int(new(big.Int).SetBytes(e).Int64()),
+		}
+	}
+
+	c.lock.Lock()
+	c.keys = keys
+	c.fetchedAt = time.Now()
+	c.lock.Unlock()
+	log.Printf("🔑 Loaded %d signing keys from %s", len(keys), c.url)
+	return nil
+}
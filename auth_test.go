@@ -0,0 +1,137 @@
+package main
+
+import (
+	"context"
+	"crypto/rsa"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestJWTModeRequiresAudience(t *testing.T) {
+	defer func(mode, jwksURL, audience string) {
+		authMode, authJWTJWKSURL, authJWTAudience = mode, jwksURL, audience
+	}(authMode, authJWTJWKSURL, authJWTAudience)
+
+	authMode, authJWTJWKSURL, authJWTAudience = "jwt", "https://issuer.example/jwks", ""
+	if _, err := newAuthenticator(); err == nil {
+		t.Fatal("expected jwt mode without AUTH_JWT_AUDIENCE to be rejected")
+	}
+
+	authJWTAudience = "mcp-helper"
+	if _, err := newAuthenticator(); err != nil {
+		t.Fatalf("unexpected error with an audience set: %v", err)
+	}
+}
+
+func TestAdminRoutesRequireAdminToken(t *testing.T) {
+	mux := http.NewServeMux()
+	registerAdminRoutes(mux, newTestHelper(t), &bearerAuthenticator{token: "admin-secret"})
+
+	for _, path := range []string{"/admin/probe", "/admin/maintenance", "/admin/refresh", "/admin/sessions"} {
+		for _, token := range []string{"", "client-token"} {
+			req := httptest.NewRequest(http.MethodGet, path, nil)
+			if token != "" {
+				req.Header.Set("Authorization", "Bearer "+token)
+			}
+			rec := httptest.NewRecorder()
+			mux.ServeHTTP(rec, req)
+			if rec.Code != http.StatusUnauthorized {
+				t.Errorf("%s with token %q: got status %d, want 401", path, token, rec.Code)
+			}
+		}
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/admin/maintenance", nil)
+	req.Header.Set("Authorization", "Bearer admin-secret")
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, req)
+	if rec.Code == http.StatusUnauthorized {
+		t.Fatal("admin token was rejected")
+	}
+}
+
+func TestJWKSFetchDoesNotBlockCachedKeys(t *testing.T) {
+	release := make(chan struct{})
+	jwks := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+		w.Write([]byte(`{"keys":[]}`))
+	}))
+	defer jwks.Close()
+	defer close(release)
+
+	cached := &rsa.PublicKey{N: big.NewInt(1), E: 65537}
+	cache := &jwksCache{
+		url:       jwks.URL,
+		keys:      map[string]*rsa.PublicKey{"cached": cached},
+		fetchedAt: time.Now().Add(-time.Minute),
+	}
+
+	// An unknown key ID triggers a fetch that hangs until released
+	go cache.key(context.Background(), "unknown")
+	time.Sleep(50 * time.Millisecond)
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := cache.key(context.Background(), "cached")
+		done <- err
+	}()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("cached key lookup failed: %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("cached key lookup blocked behind the JWKS fetch")
+	}
+}
+
+func TestBearerClientsShareOnePrincipal(t *testing.T) {
+	auth := &bearerAuthenticator{token: "shared-secret"}
+	req := httptest.NewRequest(http.MethodPost, "/mcp", nil)
+	req.Header.Set("Authorization", "Bearer shared-secret")
+
+	principal, err := auth.Authenticate(req)
+	if err != nil {
+		t.Fatalf("Authenticate() error = %v", err)
+	}
+	if principal != bearerPrincipal {
+		t.Errorf("principal = %q, want %q", principal, bearerPrincipal)
+	}
+}
+
+func TestJWKSFetchSurvivesCancelledCaller(t *testing.T) {
+	release := make(chan struct{})
+	jwks := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+		w.Write([]byte(`{"keys":[{"kid":"rotated","kty":"RSA","n":"AQAB","e":"AQAB"}]}`))
+	}))
+	defer jwks.Close()
+
+	cache := &jwksCache{url: jwks.URL, keys: make(map[string]*rsa.PublicKey)}
+
+	// The first caller starts the fetch and gives up while it is in flight
+	ctx, cancel := context.WithCancel(context.Background())
+	go cache.key(ctx, "rotated")
+	time.Sleep(50 * time.Millisecond)
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := cache.key(context.Background(), "rotated")
+		done <- err
+	}()
+	time.Sleep(50 * time.Millisecond)
+	cancel()
+	close(release)
+
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("waiting caller failed with the first caller's cancellation: %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("waiting caller never got the key")
+	}
+}
@@ -0,0 +1,22 @@
+package main
+
+import "log"
+
+// markBackendUnavailable takes a backend that failed to initialize at startup out of service under
+// DEGRADED_STARTUP. It contributes no tools, new sessions skip it, and ext-proc rejects calls to it
+// until a tool refresh reaches it again.
+func (g *MCPHelper) markBackendUnavailable(backend string, err error) {
+	log.Printf("⚠️ Backend %s failed to initialize, starting without it: %v", backend, err)
+
+	g.toolsLock.Lock()
+	g.unavailable[backend] = err.Error()
+	g.toolsLock.Unlock()
+}
+
+// BackendUnavailable implements extProc.BackendAvailability
+func (g *MCPHelper) BackendUnavailable(backend string) (string, bool) {
+	g.toolsLock.RLock()
+	defer g.toolsLock.RUnlock()
+	reason, unavailable := g.unavailable[backend]
+	return reason, unavailable
+}
@@ -0,0 +1,230 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+	"unicode"
+
+	configFile "mcp-helper/config"
+	extProc "mcp-helper/ext-proc"
+)
+
+// BackendConfig describes one MCP server whose tools the helper aggregates
+type BackendConfig struct {
+	Name            string // Backend name, also the ext-proc route target set on x-mcp-server
+	URL             string
+	Prefix          string        // Flat prefix added to the backend's tool names, "<name>-" by default
+	ToolGroup       string        // Optional group exposing tools as "<group><separator><tool>" instead of the prefix
+	SessionHeader   string        // Header the backend uses to carry its session ID
+	RequiresSession bool          // Whether the backend must hand out a session ID; stateless backends set this to false
+	InitParams      string        // Extra initialize params, a JSON object merged into the helper's own
+	Optional        bool          // Startup and new sessions carry on without the backend when it can't be reached
+	ResponseTimeout time.Duration // Tool call response timeout from the config file, 0 for none
+	InitTimeout     time.Duration // Initialize timeout overriding INIT_TIMEOUT and DISCOVERY_TIMEOUT, 0 for the defaults
+	WarmUp          string        // Primes new backend sessions: "tools/list" or a backend tool called without arguments
+	LogBodies       bool          // Log the backend's routed request and response bodies, redacted, for targeted debugging
+}
+
+// Backend names, each configured by <NAME>_URL, <NAME>_PREFIX, <NAME>_SESSION_HEADER, <NAME>_REQUIRES_SESSION,
+// <NAME>_INIT_PARAMS, <NAME>_INIT_TIMEOUT, <NAME>_TOOL_GROUP, <NAME>_WARMUP and <NAME>_LOG_BODIES, where NAME is the
+// upper-cased backend name
+var backendNames = getEnv("BACKENDS", "server1,server2")
+
+// How backends sharing a URL are handled: "reject" fails startup, "warn" logs and carries on, for one
+// server deliberately exposed under several logical names. Each name still gets its own backend sessions.
+var duplicateBackendURLs = getEnv("DUPLICATE_BACKEND_URLS", "reject")
+
+// Separator between a backend's tool group and its tool names
+var toolGroupSeparator = getEnv("TOOL_GROUP_SEPARATOR", "/")
+
+// URLs of the bundled example servers, used when their <NAME>_URL isn't set
+var defaultBackendURLs = map[string]string{
+	"server1": "http://localhost:8081",
+	"server2": "http://localhost:8082",
+}
+
+// backendEnvName returns the environment variable prefix for a backend, e.g. "my-server" -> "MY_SERVER"
+func backendEnvName(name string) string {
+	return strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			return unicode.ToUpper(r)
+		}
+		return '_'
+	}, name)
+}
+
+// loadBackends reads the backend list and each backend's settings from the environment
+func loadBackends() ([]BackendConfig, error) {
+	var loaded []BackendConfig
+	seenPrefixes := make(map[string]string)
+	for _, name := range strings.Split(backendNames, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		if _, exists := findBackendIn(loaded, name); exists {
+			return nil, fmt.Errorf("backend %s is listed more than once", name)
+		}
+
+		env := backendEnvName(name)
+		rawURL := getEnv(env+"_URL", defaultBackendURLs[name])
+		if rawURL == "" {
+			return nil, fmt.Errorf("backend %s has no URL, set %s_URL", name, env)
+		}
+		backendURL, err := normalizeBackendURL(name, rawURL)
+		if err != nil {
+			return nil, err
+		}
+
+		backend := BackendConfig{
+			Name:            name,
+			URL:             backendURL,
+			Prefix:          getEnv(env+"_PREFIX", name+"-"),
+			ToolGroup:       getEnv(env+"_TOOL_GROUP", ""),
+			SessionHeader:   getEnv(env+"_SESSION_HEADER", "mcp-session-id"),
+			RequiresSession: getEnv(env+"_REQUIRES_SESSION", "true") == "true",
+			InitParams:      getEnv(env+"_INIT_PARAMS", ""),
+			WarmUp:          getEnv(env+"_WARMUP", ""),
+			InitTimeout:     getEnvDuration(env+"_INIT_TIMEOUT", 0),
+			LogBodies:       getEnv(env+"_LOG_BODIES", "false") == "true",
+		}
+		if backend.Prefix == "" {
+			return nil, fmt.Errorf("backend %s has an empty tool prefix", name)
+		}
+		if other, clash := seenPrefixes[backend.Prefix]; clash {
+			return nil, fmt.Errorf("backends %s and %s share the tool prefix %q", other, name, backend.Prefix)
+		}
+		seenPrefixes[backend.Prefix] = name
+
+		loaded = append(loaded, backend)
+	}
+
+	if len(loaded) == 0 {
+		return nil, fmt.Errorf("no backends configured, set BACKENDS")
+	}
+	return loaded, nil
+}
+
+// backendsFromConfig converts the backends of a loaded config file, skipping disabled ones
+func backendsFromConfig(config *configFile.Config) ([]BackendConfig, error) {
+	var loaded []BackendConfig
+	for _, backend := range config.EnabledBackends() {
+		backendURL, err := normalizeBackendURL(backend.Name, backend.URL)
+		if err != nil {
+			return nil, err
+		}
+
+		var initParams string
+		if len(backend.InitParams) > 0 {
+			encoded, err := json.Marshal(backend.InitParams)
+			if err != nil {
+				return nil, fmt.Errorf("invalid init_params for backend %s: %w", backend.Name, err)
+			}
+			initParams = string(encoded)
+		}
+
+		sessionHeader := backend.SessionHeader
+		if sessionHeader == "" {
+			sessionHeader = "mcp-session-id"
+		}
+
+		loaded = append(loaded, BackendConfig{
+			Name:            backend.Name,
+			URL:             backendURL,
+			Prefix:          backend.Prefix,
+			ToolGroup:       backend.ToolGroup,
+			SessionHeader:   sessionHeader,
+			RequiresSession: backend.RequiresSession == nil || *backend.RequiresSession,
+			InitParams:      initParams,
+			Optional:        backend.Optional,
+			ResponseTimeout: backend.Timeout,
+			WarmUp:          backend.WarmUp,
+			InitTimeout:     backend.InitTimeout,
+			LogBodies:       backend.LogBodies,
+		})
+	}
+	return loaded, nil
+}
+
+// checkDuplicateURLs rejects or warns about backends configured with the same URL, which is almost
+// always a copy-paste mistake that registers one server's tools twice under different prefixes
+func checkDuplicateURLs(list []BackendConfig) error {
+	if duplicateBackendURLs != "reject" && duplicateBackendURLs != "warn" {
+		return fmt.Errorf("invalid DUPLICATE_BACKEND_URLS %q: must be reject or warn", duplicateBackendURLs)
+	}
+
+	seen := make(map[string]string)
+	for _, backend := range list {
+		other, duplicate := seen[backend.URL]
+		if !duplicate {
+			seen[backend.URL] = backend.Name
+			continue
+		}
+		if duplicateBackendURLs == "reject" {
+			return fmt.Errorf("backends %s and %s share the URL %s, set DUPLICATE_BACKEND_URLS=warn if this is intended",
+				other, backend.Name, redactURL(backend.URL))
+		}
+		log.Printf("⚠️ Backends %s and %s share the URL %s, its tools will be registered under both prefixes",
+			other, backend.Name, redactURL(backend.URL))
+	}
+	return nil
+}
+
+// registerBackendRoutes tells ext-proc how to route and name each backend's tools.
+// It must run before aggregation, which names tools with extProc.ToolPrefix.
+func registerBackendRoutes(backends []BackendConfig) error {
+	for _, backend := range backends {
+		extProc.AddBackend(backend.Name, backend.Prefix)
+		extProc.SetSessionHeader(backend.Name, backend.SessionHeader)
+		extProc.SetLogBodies(backend.Name, backend.LogBodies)
+		if err := extProc.SetToolGroup(backend.Name, backend.ToolGroup, toolGroupSeparator); err != nil {
+			return fmt.Errorf("invalid tool group for backend %s: %w", backend.Name, err)
+		}
+		log.Printf("Backend %s at %s (prefix %s)", backend.Name, redactURL(backend.URL), extProc.ToolPrefix(backend.Name))
+	}
+	return nil
+}
+
+// initTimeout returns the backend's initialize timeout, or fallback when it doesn't set one
+func (b BackendConfig) initTimeout(fallback time.Duration) time.Duration {
+	if b.InitTimeout > 0 {
+		return b.InitTimeout
+	}
+	return fallback
+}
+
+// sessionInitTimeout bounds creating all of a session's backend connections: the default init timeout,
+// stretched so a backend with a longer init timeout of its own gets its full window
+func (g *MCPHelper) sessionInitTimeout(fallback time.Duration) time.Duration {
+	timeout := fallback
+	for _, backend := range g.backends {
+		timeout = max(timeout, backend.initTimeout(fallback))
+	}
+	return timeout
+}
+
+// findBackend returns the configuration of a backend by name
+func (g *MCPHelper) findBackend(name string) (BackendConfig, bool) {
+	return findBackendIn(g.backends, name)
+}
+
+func findBackendIn(list []BackendConfig, name string) (BackendConfig, bool) {
+	for _, backend := range list {
+		if backend.Name == name {
+			return backend, true
+		}
+	}
+	return BackendConfig{}, false
+}
+
+// backendNameList returns the configured backend names in order
+func (g *MCPHelper) backendNameList() []string {
+	names := make([]string, 0, len(g.backends))
+	for _, backend := range g.backends {
+		names = append(names, backend.Name)
+	}
+	return names
+}
@@ -0,0 +1,157 @@
+package main
+
+import (
+	"compress/gzip"
+	"log"
+	"net/http"
+	"strings"
+)
+
+// Responses at least this large are gzipped for clients that accept it, 0 disables compression
+var compressionMinBytes = getEnvInt("COMPRESSION_MIN_BYTES", 0)
+
+// compressionMiddleware gzips large responses for clients sending Accept-Encoding: gzip.
+// SSE streams are never compressed so each event still reaches the client when it is flushed.
+func compressionMiddleware(minBytes int, next http.Handler) http.Handler {
+	if minBytes <= 0 {
+		return next
+	}
+	log.Printf("Compressing HTTP responses of %d bytes or more", minBytes)
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !acceptsGzip(r) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		gw := &gzipResponseWriter{ResponseWriter: w, minBytes: minBytes, status: http.StatusOK}
+		defer gw.finish()
+		next.ServeHTTP(gw, r)
+	})
+}
+
+// acceptsGzip reports whether the request accepts a gzip-encoded response
+func acceptsGzip(r *http.Request) bool {
+	for _, encoding := range strings.Split(r.Header.Get("Accept-Encoding"), ",") {
+		name, params, _ := strings.Cut(strings.TrimSpace(encoding), ";")
+		if strings.EqualFold(strings.TrimSpace(name), "gzip") && strings.TrimSpace(params) != "q=0" {
+			return true
+		}
+	}
+	return false
+}
+
+// gzipResponseWriter buffers the start of a response until it knows whether to compress it:
+// bodies reaching minBytes are gzipped, smaller, streamed or already-encoded ones pass through
+type gzipResponseWriter struct {
+	http.ResponseWriter
+	minBytes    int
+	status      int
+	wroteHeader bool // WriteHeader was called by the handler, held back until the decision
+	decided     bool
+	buffer      []byte
+	gz          *gzip.Writer
+}
+
+func (w *gzipResponseWriter) WriteHeader(statusCode int) {
+	if w.decided {
+		w.ResponseWriter.WriteHeader(statusCode)
+		return
+	}
+	w.status = statusCode
+	w.wroteHeader = true
+}
+
+func (w *gzipResponseWriter) Write(data []byte) (int, error) {
+	if !w.decided {
+		if !w.compressible() {
+			w.passThrough()
+			return w.ResponseWriter.Write(data)
+		}
+
+		w.buffer = append(w.buffer, data...)
+		if len(w.buffer) < w.minBytes {
+			return len(data), nil
+		}
+		if err := w.startGzip(); err != nil {
+			return 0, err
+		}
+		return len(data), nil
+	}
+
+	if w.gz != nil {
+		return w.gz.Write(data)
+	}
+	return w.ResponseWriter.Write(data)
+}
+
+// Flush sends buffered data immediately; a flush before the threshold means the response
+// is being streamed, so it is sent uncompressed
+func (w *gzipResponseWriter) Flush() {
+	if !w.decided {
+		w.passThrough()
+	}
+	if w.gz != nil {
+		if err := w.gz.Flush(); err != nil {
+			log.Printf("⚠️ Failed to flush gzip response: %v", err)
+		}
+	}
+	if flusher, ok := w.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// compressible reports whether the response may be compressed, based on its headers so far
+func (w *gzipResponseWriter) compressible() bool {
+	header := w.Header()
+	if header.Get("Content-Encoding") != "" {
+		return false
+	}
+	if strings.HasPrefix(header.Get("Content-Type"), "text/event-stream") {
+		return false
+	}
+	return w.status != http.StatusNoContent && w.status != http.StatusNotModified
+}
+
+// passThrough sends the held status and any buffered body uncompressed
+func (w *gzipResponseWriter) passThrough() {
+	w.decided = true
+	if w.wroteHeader || len(w.buffer) > 0 {
+		w.ResponseWriter.WriteHeader(w.status)
+	}
+	if len(w.buffer) > 0 {
+		if _, err := w.ResponseWriter.Write(w.buffer); err != nil {
+			log.Printf("⚠️ Failed to write response: %v", err)
+		}
+		w.buffer = nil
+	}
+}
+
+// startGzip switches the response to gzip and compresses the buffered body
+func (w *gzipResponseWriter) startGzip() error {
+	w.decided = true
+
+	header := w.Header()
+	header.Set("Content-Encoding", "gzip")
+	header.Add("Vary", "Accept-Encoding")
+	header.Del("Content-Length")
+	w.ResponseWriter.WriteHeader(w.status)
+
+	w.gz = gzip.NewWriter(w.ResponseWriter)
+	_, err := w.gz.Write(w.buffer)
+	w.buffer = nil
+	return err
+}
+
+// finish completes the response once the handler returns
+func (w *gzipResponseWriter) finish() {
+	if !w.decided {
+		w.passThrough()
+		return
+	}
+	if w.gz != nil {
+		if err := w.gz.Close(); err != nil {
+			log.Printf("⚠️ Failed to finish gzip response: %v", err)
+		}
+	}
+}
@@ -0,0 +1,293 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	extProc "mcp-helper/ext-proc"
+)
+
+// redacted replaces secret values in the config dump
+const redacted = "[REDACTED]"
+
+// effectiveConfig is the configuration the helper is running with, after env and flag resolution
+type effectiveConfig struct {
+	Port           string `json:"port"`
+	MaxConnections int    `json:"max_connections"`
+
+	Backends []backendConfig `json:"backends"`
+
+	Timeouts struct {
+		Init             string            `json:"init"`
+		InitQueue        string            `json:"init_queue"`
+		Discovery        string            `json:"discovery"`
+		DiscoveryBudget  string            `json:"discovery_budget"`
+		Shutdown         string            `json:"shutdown"`
+		HTTPDrain        string            `json:"http_drain"`
+		Keepalive        string            `json:"grpc_keepalive"`
+		KeepaliveTimeout string            `json:"grpc_keepalive_timeout"`
+		KeepaliveMinTime string            `json:"grpc_keepalive_min_time"`
+		SessionTTL       string            `json:"session_ttl"`
+		SessionReap      string            `json:"session_reap_interval"`
+		HealthCheck      string            `json:"health_check_interval"`
+		WarmUp           string            `json:"warmup"`
+		ToolRefresh      string            `json:"tool_refresh_interval"`
+		ToolsChanged     string            `json:"tools_changed_debounce"`
+		InitRetryDelay   string            `json:"backend_init_retry_delay"`
+		InitRetryMax     string            `json:"backend_init_retry_max_delay"`
+		BackendResponse  map[string]string `json:"backend_response"`
+	} `json:"timeouts"`
+
+	Limits struct {
+		GRPCMaxConcurrentStreams int     `json:"grpc_max_concurrent_streams"`
+		RateLimit                float64 `json:"rate_limit"`
+		RateLimitBurst           int     `json:"rate_limit_burst"`
+		SessionRateLimit         float64 `json:"session_rate_limit"`
+		SessionRateLimitBurst    int     `json:"session_rate_limit_burst"`
+		TrustedProxyHops         int     `json:"trusted_proxy_hops"`
+		MaxToolSchemaBytes       int     `json:"max_tool_schema_bytes"`
+		BackendInitConcurrency   int     `json:"backend_init_concurrency"`
+		DiscoveryConcurrency     int     `json:"discovery_concurrency"`
+		CompressionMinBytes      int     `json:"compression_min_bytes"`
+		MaxRequestHeaders        int     `json:"max_request_headers"`
+		MaxRequestHeaderBytes    int     `json:"max_request_header_bytes"`
+		BackendInitAttempts      int     `json:"backend_init_attempts"`
+		BackendRetryBudget       float64 `json:"backend_retry_budget"`
+		BackendRetryBudgetBurst  int     `json:"backend_retry_budget_burst"`
+	} `json:"limits"`
+
+	ExtProc struct {
+		Phases                 string                      `json:"phases"`
+		RouteFailureMode       string                      `json:"route_failure_mode"`
+		ValidateRequiredArgs   bool                        `json:"validate_required_args"`
+		LenientJSONRPC         bool                        `json:"lenient_jsonrpc"`
+		ResponseCacheTTL       string                      `json:"response_cache_ttl"`
+		ResponseCacheSize      int                         `json:"response_cache_size"`
+		CanaryRoutes           []extProc.CanaryRule        `json:"canary_routes"`
+		CanarySticky           bool                        `json:"canary_sticky"`
+		UnknownNotifications   string                      `json:"unknown_notifications"`
+		OutputSchemaValidation string                      `json:"output_schema_validation"`
+		BackendContentType     string                      `json:"backend_content_type"`
+		BackendDuration        bool                        `json:"backend_duration_header"`
+		OriginalToolName       bool                        `json:"original_toolname_header"`
+		DeadLetterLog          string                      `json:"dead_letter_log,omitempty"`
+		RedactFields           string                      `json:"redact_fields"`
+		StatusRemaps           map[int]extProc.StatusRemap `json:"status_remaps"`
+	} `json:"ext_proc"`
+
+	Auth struct {
+		Mode           string `json:"mode"`
+		BearerToken    string `json:"bearer_token,omitempty"`
+		JWTIssuer      string `json:"jwt_issuer,omitempty"`
+		JWTAudience    string `json:"jwt_audience,omitempty"`
+		JWTJWKSURL     string `json:"jwt_jwks_url,omitempty"`
+		PrincipalClaim string `json:"principal_claim"`
+		AdminToken     string `json:"admin_token,omitempty"`
+	} `json:"auth"`
+
+	MetricsFailureMode string   `json:"metrics_failure_mode"`
+	SessionHeader      string   `json:"session_header"`
+	ReadinessRequired  []string `json:"readiness_required_backends"`
+
+	StandaloneMode            bool   `json:"standalone_mode"`
+	BackendNotificationStream bool   `json:"backend_notification_stream"`
+	DegradedStartup           bool   `json:"degraded_startup"`
+	DuplicateBackendURLs      string `json:"duplicate_backend_urls"`
+	LazyInit                  bool   `json:"lazy_init"`
+	LogLevel                  string `json:"log_level"`
+	LogFormat                 string `json:"log_format"`
+}
+
+// backendConfig describes a configured backend
+type backendConfig struct {
+	Name            string `json:"name"`
+	URL             string `json:"url"`
+	Prefix          string `json:"prefix"`
+	SessionHeader   string `json:"session_header"`
+	RequiresSession bool   `json:"requires_session"`
+	InitParams      string `json:"init_params,omitempty"`
+	WarmUp          string `json:"warmup,omitempty"`
+	InitTimeout     string `json:"init_timeout,omitempty"`
+	LogBodies       bool   `json:"log_bodies"`
+}
+
+// buildEffectiveConfig collects the resolved configuration, with secrets redacted
+func buildEffectiveConfig(backends []BackendConfig, port string, maxConnections int, timeouts Timeouts, canaryRules []extProc.CanaryRule, responseTimeouts map[string]time.Duration, statusRemaps map[int]extProc.StatusRemap, readinessRequired map[string]bool) effectiveConfig {
+	var config effectiveConfig
+
+	config.Port = port
+	config.MaxConnections = maxConnections
+
+	for _, backend := range backends {
+		var initTimeout string
+		if backend.InitTimeout > 0 {
+			initTimeout = backend.InitTimeout.String()
+		}
+		config.Backends = append(config.Backends, backendConfig{
+			Name:            backend.Name,
+			URL:             redactURL(backend.URL),
+			Prefix:          extProc.ToolPrefix(backend.Name),
+			SessionHeader:   backend.SessionHeader,
+			RequiresSession: backend.RequiresSession,
+			InitParams:      backend.InitParams,
+			WarmUp:          backend.WarmUp,
+			InitTimeout:     initTimeout,
+			LogBodies:       backend.LogBodies,
+		})
+	}
+
+	config.Timeouts.Init = timeouts.Init.String()
+	config.Timeouts.InitQueue = timeouts.InitQueue.String()
+	config.Timeouts.Discovery = timeouts.Discovery.String()
+	config.Timeouts.DiscoveryBudget = timeouts.DiscoveryBudget.String()
+	config.Timeouts.Shutdown = timeouts.Shutdown.String()
+	config.Timeouts.HTTPDrain = timeouts.HTTPDrain.String()
+	config.Timeouts.Keepalive = timeouts.Keepalive.String()
+	config.Timeouts.KeepaliveTimeout = timeouts.KeepaliveTimeout.String()
+	config.Timeouts.KeepaliveMinTime = grpcKeepaliveMinTime.String()
+	config.Timeouts.SessionTTL = timeouts.SessionTTL.String()
+	config.Timeouts.SessionReap = timeouts.SessionReap.String()
+	config.Timeouts.HealthCheck = timeouts.HealthCheck.String()
+	config.Timeouts.WarmUp = timeouts.WarmUp.String()
+	config.Timeouts.ToolRefresh = timeouts.ToolRefresh.String()
+	config.Timeouts.ToolsChanged = timeouts.ToolsChanged.String()
+	config.Timeouts.InitRetryDelay = backendInitRetryDelay.String()
+	config.Timeouts.InitRetryMax = backendInitRetryMaxDelay.String()
+	config.Timeouts.BackendResponse = make(map[string]string)
+	for target, timeout := range responseTimeouts {
+		config.Timeouts.BackendResponse[target] = timeout.String()
+	}
+
+	config.Limits.GRPCMaxConcurrentStreams = grpcMaxConcurrentStreams
+	config.Limits.RateLimit = rateLimit
+	config.Limits.RateLimitBurst = rateLimitBurst
+	config.Limits.SessionRateLimit = sessionRateLimit
+	config.Limits.SessionRateLimitBurst = sessionRateLimitBurst
+	config.Limits.TrustedProxyHops = trustedProxyHops
+	config.Limits.MaxToolSchemaBytes = maxToolSchemaBytes
+	config.Limits.BackendInitConcurrency = backendInitConcurrency
+	config.Limits.DiscoveryConcurrency = discoveryConcurrency
+	config.Limits.CompressionMinBytes = compressionMinBytes
+	config.Limits.MaxRequestHeaders = maxRequestHeaders
+	config.Limits.MaxRequestHeaderBytes = maxRequestHeaderBytes
+	config.Limits.BackendInitAttempts = backendInitAttempts
+	config.Limits.BackendRetryBudget = backendRetryBudget
+	config.Limits.BackendRetryBudgetBurst = backendRetryBudgetBurst
+
+	config.ExtProc.Phases = string(extProc.ParseProcessingPhases(extProcPhases))
+	config.ExtProc.RouteFailureMode = string(extProc.ParseRouteFailureMode(routeFailureMode))
+	config.ExtProc.ValidateRequiredArgs = validateRequiredArgs
+	config.ExtProc.LenientJSONRPC = lenientJSONRPC
+	config.ExtProc.ResponseCacheTTL = responseCacheTTL.String()
+	config.ExtProc.ResponseCacheSize = responseCacheSize
+	config.ExtProc.CanaryRoutes = canaryRules
+	config.ExtProc.CanarySticky = canarySticky
+	config.ExtProc.UnknownNotifications = string(extProc.ParseUnknownNotificationPolicy(unknownNotificationPolicy))
+	config.ExtProc.OutputSchemaValidation = string(extProc.ParseOutputSchemaValidation(outputSchemaValidation))
+	config.ExtProc.BackendContentType = backendContentType
+	config.ExtProc.BackendDuration = backendDurationHeader
+	config.ExtProc.OriginalToolName = originalToolNameHeader
+	config.ExtProc.DeadLetterLog = deadLetterLog
+	config.ExtProc.RedactFields = redactFields
+	config.ExtProc.StatusRemaps = statusRemaps
+
+	config.Auth.Mode = authMode
+	if authBearerToken != "" {
+		config.Auth.BearerToken = redacted
+	}
+	config.Auth.JWTIssuer = authJWTIssuer
+	config.Auth.JWTAudience = authJWTAudience
+	config.Auth.JWTJWKSURL = redactURL(authJWTJWKSURL)
+	config.Auth.PrincipalClaim = authPrincipalClaim
+	if adminToken != "" {
+		config.Auth.AdminToken = redacted
+	}
+
+	config.MetricsFailureMode = metricsFailureMode
+	config.SessionHeader = extProc.ClientSessionHeader()
+	for _, backend := range backends {
+		if readinessRequired[backend.Name] {
+			config.ReadinessRequired = append(config.ReadinessRequired, backend.Name)
+		}
+	}
+	config.StandaloneMode = standaloneMode
+	config.BackendNotificationStream = backendNotificationStream
+	config.DegradedStartup = degradedStartup
+	config.DuplicateBackendURLs = duplicateBackendURLs
+	config.LazyInit = lazyInit
+	config.LogLevel = logLevel
+	config.LogFormat = logFormat
+
+	return config
+}
+
+// normalizeBackendURL validates a backend URL and strips trailing slashes so it is used consistently
+func normalizeBackendURL(name, raw string) (string, error) {
+	parsed, err := url.Parse(strings.TrimSpace(raw))
+	if err != nil {
+		return "", fmt.Errorf("invalid URL for backend %s %q: %w", name, raw, err)
+	}
+	if parsed.Scheme != "http" && parsed.Scheme != "https" {
+		return "", fmt.Errorf("invalid URL for backend %s %q: scheme must be http or https", name, raw)
+	}
+	if parsed.Host == "" {
+		return "", fmt.Errorf("invalid URL for backend %s %q: missing host", name, raw)
+	}
+
+	// Trim the escaped path so encoded slashes and other escapes in the backend path survive
+	escaped := strings.TrimRight(parsed.EscapedPath(), "/")
+	parsed.Path, err = url.PathUnescape(escaped)
+	if err != nil {
+		return "", fmt.Errorf("invalid URL for backend %s %q: %w", name, raw, err)
+	}
+	parsed.RawPath = escaped
+	return parsed.String(), nil
+}
+
+// redactURL hides any credentials embedded in a URL
+func redactURL(raw string) string {
+	parsed, err := url.Parse(raw)
+	if err != nil {
+		return raw
+	}
+	return parsed.Redacted()
+}
+
+// handleConfig serves the effective configuration as JSON
+func handleConfig(config effectiveConfig) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(config); err != nil {
+			log.Printf("Failed to write config dump: %v", err)
+		}
+	}
+}
+
+// handleRoutes serves the routing table ext-proc applies as JSON, the authoritative view for debugging misroutes
+func handleRoutes(processor *extProc.Server) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(processor.RoutingTable()); err != nil {
+			log.Printf("Failed to write routing table: %v", err)
+		}
+	}
+}
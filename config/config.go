@@ -0,0 +1,106 @@
+// Package config loads the helper's backend definitions from a YAML or JSON file.
+package config
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Config is the helper configuration read from a config file
+type Config struct {
+	Backends []Backend `yaml:"backends"`
+}
+
+// Backend describes one MCP backend server
+type Backend struct {
+	Name            string         `yaml:"name"`
+	URL             string         `yaml:"url"`
+	Prefix          string         `yaml:"prefix"`           // Prefix added to the backend's tool names, e.g. "weather-"
+	Timeout         time.Duration  `yaml:"timeout"`          // Tool call response timeout, e.g. "30s"; 0 for none
+	InitTimeout     time.Duration  `yaml:"init_timeout"`     // Initialize timeout, e.g. "2s"; 0 for INIT_TIMEOUT/DISCOVERY_TIMEOUT
+	Disabled        bool           `yaml:"disabled"`         // Skipped entirely, as if it weren't listed
+	Optional        bool           `yaml:"optional"`         // Startup and new sessions carry on without it when it is down
+	ToolGroup       string         `yaml:"tool_group"`       // Optional group used instead of the prefix
+	SessionHeader   string         `yaml:"session_header"`   // Defaults to mcp-session-id
+	RequiresSession *bool          `yaml:"requires_session"` // Defaults to true; false for stateless backends
+	InitParams      map[string]any `yaml:"init_params"`      // Extra initialize params merged into the helper's own
+	WarmUp          string         `yaml:"warmup"`           // "tools/list" or a tool called without arguments on each new session
+	LogBodies       bool           `yaml:"log_bodies"`       // Log routed request and response bodies, redacted by REDACT_FIELDS
+}
+
+// LoadConfig reads and validates a config file. JSON is accepted as well as YAML.
+func LoadConfig(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config file: %w", err)
+	}
+
+	var config Config
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&config); err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("failed to parse config file %s: %w", path, err)
+	}
+
+	if err := config.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid config file %s: %w", path, err)
+	}
+	return &config, nil
+}
+
+// Validate checks every enabled backend is named, has a URL and a unique, non-empty prefix
+func (c *Config) Validate() error {
+	names := make(map[string]bool)
+	prefixes := make(map[string]string)
+	for i, backend := range c.Backends {
+		if backend.Name == "" {
+			return fmt.Errorf("backend %d has no name", i+1)
+		}
+		if names[backend.Name] {
+			return fmt.Errorf("backend %s is listed more than once", backend.Name)
+		}
+		names[backend.Name] = true
+
+		if backend.Disabled {
+			continue
+		}
+		if backend.URL == "" {
+			return fmt.Errorf("backend %s has no url", backend.Name)
+		}
+		if backend.Prefix == "" {
+			return fmt.Errorf("backend %s has no prefix", backend.Name)
+		}
+		if other, clash := prefixes[backend.Prefix]; clash {
+			return fmt.Errorf("backends %s and %s share the prefix %q, prefixes must be unique", other, backend.Name, backend.Prefix)
+		}
+		prefixes[backend.Prefix] = backend.Name
+		if backend.Timeout < 0 {
+			return fmt.Errorf("backend %s has a negative timeout", backend.Name)
+		}
+		if backend.InitTimeout < 0 {
+			return fmt.Errorf("backend %s has a negative init_timeout", backend.Name)
+		}
+	}
+
+	if len(c.EnabledBackends()) == 0 {
+		return fmt.Errorf("no enabled backends")
+	}
+	return nil
+}
+
+// EnabledBackends returns the backends that aren't disabled, in file order
+func (c *Config) EnabledBackends() []Backend {
+	var enabled []Backend
+	for _, backend := range c.Backends {
+		if !backend.Disabled {
+			enabled = append(enabled, backend)
+		}
+	}
+	return enabled
+}
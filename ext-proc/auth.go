@@ -0,0 +1,59 @@
+package handlers
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"strings"
+
+	eppb "github.com/envoyproxy/go-control-plane/envoy/service/ext_proc/v3"
+)
+
+// Authenticator validates the credentials on a request and returns the authenticated principal.
+// Routed requests go from Envoy straight to backends and never reach the helper's HTTP handler, so
+// ext-proc checks them with the same authenticator the helper uses.
+type Authenticator interface {
+	Authenticate(r *http.Request) (string, error)
+}
+
+// authenticate checks the credentials on a routed request and that they belong to the principal that
+// created its session, so a leaked session ID alone can't reach the backends. It returns the response
+// to send when the request is rejected, or nil when it may be routed.
+func (s *Server) authenticate(ctx context.Context, mapping *SessionMapping) []*eppb.ProcessingResponse {
+	if s.config.Authenticator == nil {
+		return nil
+	}
+
+	principal, err := s.config.Authenticator.Authenticate(requestFromContext(ctx))
+	if err != nil {
+		log.Printf("[EXT-PROC] 🔒 Rejecting unauthenticated routed request for session %s: %v", mapping.HelperSessionID, err)
+		return s.createErrorResponse("Unauthorized", 401)
+	}
+	if principal != mapping.Principal {
+		log.Printf("[EXT-PROC] 🔒 Principal '%s' is not the owner of session %s", principal, mapping.HelperSessionID)
+		return s.createErrorResponse("Forbidden", 403)
+	}
+	return nil
+}
+
+// requestFromContext rebuilds the HTTP request headers stored on the stream context for an Authenticator.
+// Envoy's pseudo-headers carry the method and path rather than being headers of their own.
+func requestFromContext(ctx context.Context) *http.Request {
+	r := (&http.Request{Method: http.MethodPost, Header: make(http.Header)}).WithContext(ctx)
+	requestHeaders, ok := ctx.Value(requestHeadersKey{}).(*eppb.HttpHeaders)
+	if !ok || requestHeaders == nil || requestHeaders.Headers == nil {
+		return r
+	}
+	for _, header := range requestHeaders.Headers.Headers {
+		value := string(header.RawValue)
+		switch {
+		case header.Key == ":method":
+			r.Method = value
+		case strings.HasPrefix(header.Key, ":"):
+			continue
+		default:
+			r.Header.Add(header.Key, value)
+		}
+	}
+	return r
+}
@@ -0,0 +1,61 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+
+	basepb "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
+	eppb "github.com/envoyproxy/go-control-plane/envoy/service/ext_proc/v3"
+)
+
+// tokenAuthenticator maps bearer tokens to principals
+type tokenAuthenticator map[string]string
+
+func (a tokenAuthenticator) Authenticate(r *http.Request) (string, error) {
+	principal, ok := a[strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")]
+	if !ok {
+		return "", errors.New("invalid token")
+	}
+	return principal, nil
+}
+
+func TestRoutedRequestsAreAuthenticated(t *testing.T) {
+	useBackends(t, serverConfig{prefix: "server1-", target: "server1"})
+	helper := newFakeHelper("helper-1", map[string]string{"server1": "backend-1"})
+	helper.sessions["helper-1"].Principal = "alice"
+	s := NewServer(false, helper, Config{Authenticator: tokenAuthenticator{"alice-token": "alice", "bob-token": "bob"}})
+
+	tests := []struct {
+		name   string
+		token  string
+		status int32
+	}{
+		{"no credentials", "", 401},
+		{"another principal's credentials", "bob-token", 403},
+		{"the session owner", "alice-token", 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := requestContext("helper-1")
+			if tt.token != "" {
+				headers := ctx.Value(requestHeadersKey{}).(*eppb.HttpHeaders)
+				headers.Headers.Headers = append(headers.Headers.Headers,
+					&basepb.HeaderValue{Key: "authorization", RawValue: []byte("Bearer " + tt.token)})
+			}
+
+			responses, err := s.HandleRequestBody(ctx, decodeTestBody(t, routedToolCall), &routeState{})
+			if err != nil {
+				t.Fatalf("HandleRequestBody() error = %v", err)
+			}
+			immediate := responses[0].GetImmediateResponse()
+			switch {
+			case tt.status == 0 && immediate != nil:
+				t.Fatalf("owner's call was rejected with %d", immediate.GetStatus().GetCode())
+			case tt.status != 0 && (immediate == nil || int32(immediate.GetStatus().GetCode()) != tt.status):
+				t.Fatalf("got %v, want a %d response", responses[0], tt.status)
+			}
+		})
+	}
+}
@@ -0,0 +1,143 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+)
+
+// redactedValue replaces the value of redacted fields
+const redactedValue = "[REDACTED]"
+
+// redactor replaces the values of sensitive fields, matched case-insensitively, in decoded JSON
+type redactor map[string]bool
+
+// newRedactor builds a redactor for the given field names
+func newRedactor(fields []string) redactor {
+	redact := make(redactor, len(fields))
+	for _, field := range fields {
+		if field = strings.TrimSpace(field); field != "" {
+			redact[strings.ToLower(field)] = true
+		}
+	}
+	return redact
+}
+
+// value returns a copy of a decoded JSON value with redacted fields replaced
+func (r redactor) value(value any) any {
+	switch v := value.(type) {
+	case map[string]any:
+		redacted := make(map[string]any, len(v))
+		for key, field := range v {
+			if r[strings.ToLower(key)] {
+				redacted[key] = redactedValue
+				continue
+			}
+			redacted[key] = r.value(field)
+		}
+		return redacted
+	case []any:
+		redacted := make([]any, len(v))
+		for i, item := range v {
+			redacted[i] = r.value(item)
+		}
+		return redacted
+	default:
+		return v
+	}
+}
+
+// json returns the redacted JSON encoding of a decoded value
+func (r redactor) json(value any) string {
+	encoded, err := json.Marshal(r.value(value))
+	if err != nil {
+		return "(unencodable body)"
+	}
+	return string(encoded)
+}
+
+// body redacts a response body, either a JSON document or an SSE stream of JSON events.
+// Anything else is summarised by size, since it can't be redacted.
+func (r redactor) body(body []byte) string {
+	if decoded, ok := decodeJSON(body); ok {
+		return r.json(decoded)
+	}
+
+	lines := strings.Split(string(body), "\n")
+	events := 0
+	for i, line := range lines {
+		data, isData := strings.CutPrefix(line, "data:")
+		if !isData {
+			continue
+		}
+		decoded, ok := decodeJSON([]byte(data))
+		if !ok {
+			lines[i] = "data: (non-JSON event)"
+			continue
+		}
+		lines[i] = "data: " + r.json(decoded)
+		events++
+	}
+	if events == 0 {
+		return "(non-JSON body, not logged)"
+	}
+	return strings.Join(lines, "\n")
+}
+
+// decodeJSON decodes a JSON document keeping numbers exact, as request bodies are
+func decodeJSON(data []byte) (any, bool) {
+	var decoded any
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	if err := decoder.Decode(&decoded); err != nil {
+		return nil, false
+	}
+	return decoded, true
+}
+
+// SetLogBodies turns body logging on for one backend target's routed requests and responses,
+// so a single problematic backend can be debugged without logging every backend's traffic
+func SetLogBodies(target string, on bool) {
+	for i := range serverConfigs {
+		if serverConfigs[i].target == target {
+			serverConfigs[i].logBodies = on
+			if on {
+				logger().Info("logging request and response bodies", "backend", target)
+			}
+		}
+	}
+}
+
+// logsBodies reports whether body logging is on for a backend target
+func logsBodies(target string) bool {
+	for _, config := range serverConfigs {
+		if config.target == target {
+			return config.logBodies
+		}
+	}
+	return false
+}
+
+// logRequestBody logs the rewritten body of a routed request, redacted, when its backend logs bodies
+func (s *Server) logRequestBody(target, name, helperSession string, data map[string]any) {
+	if !logsBodies(target) {
+		return
+	}
+	logger().Info("request body",
+		"backend", target,
+		"tool", name,
+		"session_id", helperSession,
+		"body", s.redact.json(data))
+}
+
+// logResponseBody logs a routed response body, redacted, when its backend logs bodies
+func (s *Server) logResponseBody(route *routeState, body []byte) {
+	if route == nil || route.target == "" || len(body) == 0 || !logsBodies(route.target) {
+		return
+	}
+	logger().Info("response body",
+		"backend", route.target,
+		"tool", route.name,
+		"session_id", route.helperSession,
+		"body", s.redact.body(body))
+}
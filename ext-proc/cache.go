@@ -0,0 +1,125 @@
+package handlers
+
+import (
+	"container/list"
+	"encoding/json"
+	"log"
+	"sync"
+	"time"
+)
+
+// cacheEntry is a cached JSON-RPC result for a read-only tool call
+type cacheEntry struct {
+	key       string
+	result    json.RawMessage
+	expiresAt time.Time
+}
+
+// responseCache caches results of read-only tool calls keyed on caller, backend, tool and arguments.
+// It holds at most maxEntries results, evicting the least recently used one to make room.
+type responseCache struct {
+	ttl        time.Duration
+	maxEntries int
+	entries    map[string]*list.Element // key -> element of order holding a *cacheEntry
+	order      *list.List               // most recently used first
+	lock       sync.Mutex
+}
+
+func newResponseCache(ttl time.Duration, maxEntries int) *responseCache {
+	return &responseCache{
+		ttl:        ttl,
+		maxEntries: maxEntries,
+		entries:    make(map[string]*list.Element),
+		order:      list.New(),
+	}
+}
+
+// cacheKey builds the cache key for a tool call on a backend. Results are scoped to the caller - the
+// principal when authenticated, otherwise the backend session - so one client never sees another's result.
+func cacheKey(scope, target, toolName string, arguments any) (string, bool) {
+	// Canonical arguments so key ordering and number formatting don't split cache entries
+	argBytes, err := CanonicalJSON(arguments)
+	if err != nil {
+		log.Printf("[EXT-PROC] Failed to build cache key for %s/%s: %v", target, toolName, err)
+		return "", false
+	}
+	return scope + "|" + target + "|" + toolName + "|" + string(argBytes), true
+}
+
+// get returns the cached result for a key if present and not expired
+func (c *responseCache) get(key string) (json.RawMessage, bool) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	element, exists := c.entries[key]
+	if !exists {
+		return nil, false
+	}
+	entry := element.Value.(*cacheEntry)
+	if time.Now().After(entry.expiresAt) {
+		c.remove(element)
+		return nil, false
+	}
+	c.order.MoveToFront(element)
+	return entry.result, true
+}
+
+// put stores a result for a key for the configured TTL, evicting the least recently used results over the limit
+func (c *responseCache) put(key string, result json.RawMessage) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	expiresAt := time.Now().Add(c.ttl)
+	if element, exists := c.entries[key]; exists {
+		entry := element.Value.(*cacheEntry)
+		entry.result, entry.expiresAt = result, expiresAt
+		c.order.MoveToFront(element)
+		return
+	}
+	c.entries[key] = c.order.PushFront(&cacheEntry{key: key, result: result, expiresAt: expiresAt})
+	for c.maxEntries > 0 && c.order.Len() > c.maxEntries {
+		c.remove(c.order.Back())
+	}
+}
+
+// remove drops an entry; the caller holds the lock
+func (c *responseCache) remove(element *list.Element) {
+	c.order.Remove(element)
+	delete(c.entries, element.Value.(*cacheEntry).key)
+}
+
+// len returns the number of cached results, including expired ones not yet evicted
+func (c *responseCache) len() int {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return c.order.Len()
+}
+
+// storeFromResponseBody caches the result of a successful JSON-RPC tool call response
+func (c *responseCache) storeFromResponseBody(key string, body []byte) {
+	var response struct {
+		Result json.RawMessage `json:"result"`
+		Error  json.RawMessage `json:"error"`
+	}
+	if err := json.Unmarshal(body, &response); err != nil {
+		log.Printf("[EXT-PROC] Response not cacheable, not a JSON-RPC body: %v", err)
+		return
+	}
+	if len(response.Error) > 0 || len(response.Result) == 0 {
+		log.Println("[EXT-PROC] Response not cacheable, no successful result")
+		return
+	}
+
+	// Tool-level failures are reported in the result and must not be cached
+	var result struct {
+		IsError bool `json:"isError"`
+	}
+	if err := json.Unmarshal(response.Result, &result); err == nil && result.IsError {
+		log.Println("[EXT-PROC] Response not cacheable, tool returned an error result")
+		return
+	}
+
+	// The key carries the call's arguments, which are never logged
+	c.put(key, response.Result)
+	log.Printf("[EXT-PROC] 💾 Cached response (ttl %s)", c.ttl)
+}
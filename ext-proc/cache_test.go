@@ -0,0 +1,93 @@
+package handlers
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestResponseCacheEvictsLeastRecentlyUsed(t *testing.T) {
+	cache := newResponseCache(time.Minute, 2)
+	cache.put("a", json.RawMessage(`1`))
+	cache.put("b", json.RawMessage(`2`))
+	if _, hit := cache.get("a"); !hit {
+		t.Fatal("a was not cached")
+	}
+	cache.put("c", json.RawMessage(`3`))
+
+	if _, hit := cache.get("b"); hit {
+		t.Error("b, the least recently used result, was not evicted")
+	}
+	for _, key := range []string{"a", "c"} {
+		if _, hit := cache.get(key); !hit {
+			t.Errorf("%s was evicted", key)
+		}
+	}
+	if size := cache.len(); size != 2 {
+		t.Errorf("cache holds %d results, want 2", size)
+	}
+}
+
+func TestResponseCacheExpires(t *testing.T) {
+	cache := newResponseCache(time.Nanosecond, 0)
+	cache.put("a", json.RawMessage(`1`))
+	time.Sleep(time.Millisecond)
+
+	if _, hit := cache.get("a"); hit {
+		t.Error("expired result was served")
+	}
+	if size := cache.len(); size != 0 {
+		t.Errorf("cache holds %d results after expiry, want 0", size)
+	}
+}
+
+func TestCachedResultsAreScopedToTheCaller(t *testing.T) {
+	tests := []struct {
+		name            string
+		principals      [2]string
+		backendSessions [2]string
+		sameSession     bool
+		shared          bool
+	}{
+		{name: "same helper session", backendSessions: [2]string{"backend-1", "backend-1"}, sameSession: true, shared: true},
+		{name: "anonymous sessions on one backend session", backendSessions: [2]string{"backend-1", "backend-1"}},
+		{name: "anonymous sessions on a stateless backend", backendSessions: [2]string{"", ""}},
+		{name: "other backend session", backendSessions: [2]string{"backend-1", "backend-2"}},
+		{name: "same principal", principals: [2]string{"alice", "alice"}, backendSessions: [2]string{"backend-1", "backend-2"}, shared: true},
+		{name: "other principal", principals: [2]string{"alice", "bob"}, backendSessions: [2]string{"backend-1", "backend-1"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			useBackends(t, serverConfig{prefix: "server1-", target: "server1"})
+			helper := newSchemaHelper()
+			helper.readOnly = map[string]bool{"server1-echo": true}
+			helper.sessions = map[string]*SessionMapping{
+				"helper-1": {HelperSessionID: "helper-1", Principal: tt.principals[0], BackendSessions: map[string]string{"server1": tt.backendSessions[0]}},
+				"helper-2": {HelperSessionID: "helper-2", Principal: tt.principals[1], BackendSessions: map[string]string{"server1": tt.backendSessions[1]}},
+			}
+			s := NewServer(false, helper, Config{ResponseCacheTTL: time.Minute, ResponseCacheSize: 10})
+
+			// The first caller's result is cached from the backend response
+			route := &routeState{}
+			if _, err := s.HandleRequestBody(requestContext("helper-1"), decodeTestBody(t, routedToolCall), route); err != nil {
+				t.Fatalf("HandleRequestBody() error = %v", err)
+			}
+			if route.cacheKey == "" {
+				t.Fatal("read-only tool call was not marked for caching")
+			}
+			s.cache.storeFromResponseBody(route.cacheKey, []byte(`{"jsonrpc":"2.0","id":1,"result":{"content":[]}}`))
+
+			second := "helper-2"
+			if tt.sameSession {
+				second = "helper-1"
+			}
+			responses, err := s.HandleRequestBody(requestContext(second), decodeTestBody(t, routedToolCall), &routeState{})
+			if err != nil {
+				t.Fatalf("HandleRequestBody() error = %v", err)
+			}
+			if served := responses[0].GetImmediateResponse() != nil; served != tt.shared {
+				t.Errorf("second caller served from cache = %t, want %t", served, tt.shared)
+			}
+		})
+	}
+}
@@ -0,0 +1,101 @@
+package handlers
+
+import (
+	"fmt"
+	"hash/fnv"
+	"log"
+	"math/rand"
+	"strconv"
+	"strings"
+)
+
+// CanaryRule sends a percentage of calls for a backend, or a single tool, to a canary target.
+// The canary must accept the stable backend's session IDs (true for mcp-go backends).
+type CanaryRule struct {
+	Match        string // stable target (e.g. "server1") or prefixed tool name (e.g. "server1-echo")
+	CanaryTarget string // routing target for canary calls, matched by Envoy on x-mcp-server
+	Percent      int    // share of calls sent to the canary, 0-100
+}
+
+// CanaryConfig configures percentage-based canary routing
+type CanaryConfig struct {
+	Rules []CanaryRule
+	// Sticky assigns by helper session so a client always sees the same backend version,
+	// otherwise each call is assigned at random
+	Sticky bool
+}
+
+// ParseCanaryRules parses rules of the form "<target-or-tool>=<canary-target>:<percent>,..."
+func ParseCanaryRules(spec string) ([]CanaryRule, error) {
+	var rules []CanaryRule
+	for _, entry := range strings.Split(spec, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		match, rest, ok := strings.Cut(entry, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid canary rule %q: expected <target-or-tool>=<canary-target>:<percent>", entry)
+		}
+		canaryTarget, percentStr, ok := strings.Cut(rest, ":")
+		if !ok {
+			return nil, fmt.Errorf("invalid canary rule %q: missing percentage", entry)
+		}
+		percent, err := strconv.Atoi(percentStr)
+		if err != nil || percent < 0 || percent > 100 {
+			return nil, fmt.Errorf("invalid canary rule %q: percentage must be 0-100", entry)
+		}
+
+		rules = append(rules, CanaryRule{
+			Match:        strings.TrimSpace(match),
+			CanaryTarget: strings.TrimSpace(canaryTarget),
+			Percent:      percent,
+		})
+	}
+	return rules, nil
+}
+
+// findCanaryRule returns the rule for a tool call, preferring a per-tool rule over a per-backend one
+func (c CanaryConfig) findCanaryRule(toolName, routeTarget string) (CanaryRule, bool) {
+	var backendRule *CanaryRule
+	for i, rule := range c.Rules {
+		if rule.Match == toolName {
+			return rule, true
+		}
+		if rule.Match == routeTarget && backendRule == nil {
+			backendRule = &c.Rules[i]
+		}
+	}
+	if backendRule != nil {
+		return *backendRule, true
+	}
+	return CanaryRule{}, false
+}
+
+// selectCanaryTarget returns the target a tool call should be sent to and whether it is a canary
+func (c CanaryConfig) selectCanaryTarget(toolName, routeTarget, helperSession string) (string, bool) {
+	rule, found := c.findCanaryRule(toolName, routeTarget)
+	if !found || rule.Percent == 0 {
+		return routeTarget, false
+	}
+
+	var bucket int
+	if c.Sticky {
+		hash := fnv.New32a()
+		hash.Write([]byte(helperSession))
+		bucket = int(hash.Sum32() % 100)
+	} else {
+		bucket = rand.Intn(100)
+	}
+
+	if bucket < rule.Percent {
+		log.Printf("[EXT-PROC] 🐤 Canary assignment: %s -> %s (%d%%, bucket %d, sticky %v)",
+			toolName, rule.CanaryTarget, rule.Percent, bucket, c.Sticky)
+		return rule.CanaryTarget, true
+	}
+
+	log.Printf("[EXT-PROC] Stable assignment: %s -> %s (canary %d%%, bucket %d, sticky %v)",
+		toolName, routeTarget, rule.Percent, bucket, c.Sticky)
+	return routeTarget, false
+}
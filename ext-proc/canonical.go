@@ -0,0 +1,124 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// CanonicalJSON returns a canonical JSON encoding of v: object keys sorted, no insignificant
+// whitespace and numbers in their shortest form. Semantically identical values encode to the
+// same bytes, which makes the result suitable for cache keys, dedup and argument hashing.
+func CanonicalJSON(v any) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := writeCanonical(&buf, v); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func writeCanonical(buf *bytes.Buffer, v any) error {
+	switch value := v.(type) {
+	case nil:
+		buf.WriteString("null")
+	case bool:
+		buf.WriteString(strconv.FormatBool(value))
+	case string:
+		encoded, err := json.Marshal(value)
+		if err != nil {
+			return err
+		}
+		buf.Write(encoded)
+	case json.Number:
+		number, err := canonicalNumber(value.String())
+		if err != nil {
+			return err
+		}
+		buf.WriteString(number)
+	case float64:
+		number, err := canonicalNumber(strconv.FormatFloat(value, 'g', -1, 64))
+		if err != nil {
+			return err
+		}
+		buf.WriteString(number)
+	case map[string]any:
+		keys := make([]string, 0, len(value))
+		for key := range value {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+
+		buf.WriteByte('{')
+		for i, key := range keys {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			if err := writeCanonical(buf, key); err != nil {
+				return err
+			}
+			buf.WriteByte(':')
+			if err := writeCanonical(buf, value[key]); err != nil {
+				return err
+			}
+		}
+		buf.WriteByte('}')
+	case []any:
+		buf.WriteByte('[')
+		for i, item := range value {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			if err := writeCanonical(buf, item); err != nil {
+				return err
+			}
+		}
+		buf.WriteByte(']')
+	default:
+		// Anything else (raw JSON, typed structs, other number types) is round-tripped
+		// through a generic decode so it is canonicalized the same way
+		var raw []byte
+		if rawMessage, ok := value.(json.RawMessage); ok {
+			raw = rawMessage
+		} else {
+			encoded, err := json.Marshal(value)
+			if err != nil {
+				return fmt.Errorf("failed to encode %T: %w", value, err)
+			}
+			raw = encoded
+		}
+
+		decoder := json.NewDecoder(bytes.NewReader(raw))
+		decoder.UseNumber()
+		var generic any
+		if err := decoder.Decode(&generic); err != nil {
+			return fmt.Errorf("failed to decode %T: %w", value, err)
+		}
+		return writeCanonical(buf, generic)
+	}
+	return nil
+}
+
+// canonicalNumber renders a JSON number in its shortest form, so 1, 1.0 and 1e0 are identical
+func canonicalNumber(number string) (string, error) {
+	if !strings.ContainsAny(number, ".eE") {
+		if parsed, err := strconv.ParseInt(number, 10, 64); err == nil {
+			return strconv.FormatInt(parsed, 10), nil
+		}
+	}
+
+	parsed, err := strconv.ParseFloat(number, 64)
+	if err != nil {
+		return "", fmt.Errorf("invalid number %q: %w", number, err)
+	}
+	if parsed == 0 {
+		return "0", nil
+	}
+	if parsed == math.Trunc(parsed) && math.Abs(parsed) < 1e21 {
+		return strconv.FormatFloat(parsed, 'f', -1, 64), nil
+	}
+	return strconv.FormatFloat(parsed, 'g', -1, 64), nil
+}
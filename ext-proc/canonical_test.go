@@ -0,0 +1,76 @@
+package handlers
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestCanonicalJSON(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{name: "keys sorted", input: `{"b":1,"a":2}`, want: `{"a":2,"b":1}`},
+		{name: "nested keys sorted", input: `{"z":{"y":1,"x":[{"d":1,"c":2}]}}`, want: `{"z":{"x":[{"c":2,"d":1}],"y":1}}`},
+		{name: "whitespace dropped", input: "{ \"a\" :\n[ 1 , 2 ] }", want: `{"a":[1,2]}`},
+		{name: "array order kept", input: `[3,1,2]`, want: `[3,1,2]`},
+		{name: "integral float", input: `1.0`, want: `1`},
+		{name: "exponent", input: `1e0`, want: `1`},
+		{name: "large exponent", input: `1e2`, want: `100`},
+		{name: "fraction", input: `0.50`, want: `0.5`},
+		{name: "negative zero", input: `-0.0`, want: `0`},
+		{name: "integer above 2^53", input: `9007199254740993`, want: `9007199254740993`},
+		{name: "huge number", input: `1e21`, want: `1e+21`},
+		{name: "escaped string", input: `"ab\n"`, want: `"ab\n"`},
+		{name: "literals", input: `[true,false,null]`, want: `[true,false,null]`},
+		{name: "empty containers", input: `{"a":{},"b":[]}`, want: `{"a":{},"b":[]}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			value, ok := decodeJSON([]byte(tt.input))
+			if !ok {
+				t.Fatalf("decoding %s failed", tt.input)
+			}
+			got, err := CanonicalJSON(value)
+			if err != nil {
+				t.Fatalf("CanonicalJSON() error = %v", err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("CanonicalJSON(%s) = %s, want %s", tt.input, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCanonicalJSONOtherTypes(t *testing.T) {
+	want := `{"a":1,"b":"x"}`
+	tests := []struct {
+		name  string
+		input any
+	}{
+		{name: "raw message", input: json.RawMessage(`{"b":"x", "a":1.0}`)},
+		{name: "struct", input: struct {
+			B string `json:"b"`
+			A int    `json:"a"`
+		}{B: "x", A: 1}},
+		{name: "float64 values", input: map[string]any{"b": "x", "a": float64(1)}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := CanonicalJSON(tt.input)
+			if err != nil {
+				t.Fatalf("CanonicalJSON() error = %v", err)
+			}
+			if string(got) != want {
+				t.Errorf("CanonicalJSON() = %s, want %s", got, want)
+			}
+		})
+	}
+}
+
+func TestCanonicalJSONInvalidNumber(t *testing.T) {
+	if _, err := CanonicalJSON(json.Number("not-a-number")); err == nil {
+		t.Error("CanonicalJSON() accepted an invalid number")
+	}
+}
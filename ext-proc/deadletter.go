@@ -0,0 +1,93 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"sync"
+	"time"
+)
+
+// DeadLetterLog records tool calls that failed routing, with sensitive fields redacted,
+// so recurring client errors can be analysed after the fact
+type DeadLetterLog struct {
+	out    io.Writer
+	closer io.Closer
+	redact redactor
+	lock   sync.Mutex
+}
+
+// deadLetter is one failed request as written to the dead-letter log
+type deadLetter struct {
+	Time          string `json:"time"`
+	Reason        string `json:"reason"`
+	Status        int32  `json:"status"`
+	FailOpen      bool   `json:"fail_open"`
+	HelperSession string `json:"helper_session,omitempty"`
+	Request       any    `json:"request"`
+}
+
+// NewDeadLetterLog opens a dead-letter log appending JSON lines to path ("-" for stdout).
+// Fields named in redactFields are redacted wherever they appear in the request, matched case-insensitively.
+func NewDeadLetterLog(path string, redactFields []string) (*DeadLetterLog, error) {
+	deadLetters := &DeadLetterLog{redact: newRedactor(redactFields)}
+
+	if path == "-" {
+		deadLetters.out = os.Stdout
+		return deadLetters, nil
+	}
+
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open dead-letter log: %w", err)
+	}
+	deadLetters.out = file
+	deadLetters.closer = file
+	return deadLetters, nil
+}
+
+// Close closes the underlying sink
+func (d *DeadLetterLog) Close() error {
+	if d == nil || d.closer == nil {
+		return nil
+	}
+	return d.closer.Close()
+}
+
+// record writes a failed request and the failure reason
+func (d *DeadLetterLog) record(reason string, status int32, failOpen bool, helperSession string, request map[string]any) {
+	if d == nil {
+		return
+	}
+
+	entry, err := json.Marshal(deadLetter{
+		Time:          time.Now().UTC().Format(time.RFC3339Nano),
+		Reason:        reason,
+		Status:        status,
+		FailOpen:      failOpen,
+		HelperSession: helperSession,
+		Request:       d.redact.value(request),
+	})
+	if err != nil {
+		log.Printf("[EXT-PROC] Failed to encode dead letter: %v", err)
+		return
+	}
+
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	if _, err := d.out.Write(append(entry, '\n')); err != nil {
+		log.Printf("[EXT-PROC] Failed to write dead letter: %v", err)
+	}
+}
+
+// deadLetter records a request that failed routing, when a dead-letter log is configured
+func (s *Server) deadLetter(ctx context.Context, data map[string]any, reason string, status int32) {
+	if s.config.DeadLetters == nil {
+		return
+	}
+	s.config.DeadLetters.record(reason, status, s.config.RouteFailureMode == RouteFailureOpen,
+		s.extractSessionFromContext(ctx), data)
+}
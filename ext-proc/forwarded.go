@@ -0,0 +1,61 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"time"
+)
+
+// Tool calls the helper forwards over its own backend connections, such as helper_call, never reach
+// HandleRequestBody. The methods below apply the same tool call policies to them, so calling a tool through
+// the helper is no way around them. They are safe to call on a nil Server, which applies no policies.
+
+// CheckForwardedToolCall applies the rate limit and required argument validation of a routed tools/call
+// to one the helper forwards itself, and returns the backend response timeout to apply, 0 for none.
+// request is the tools/call JSON-RPC request, carrying the aggregated tool name.
+func (s *Server) CheckForwardedToolCall(helperSession, principal, toolName, target string, request map[string]any) (time.Duration, error) {
+	if s == nil {
+		return 0, nil
+	}
+
+	if !s.allowCall(helperSession, principal) {
+		return 0, errors.New("Rate limit exceeded")
+	}
+	if s.config.ValidateRequiredArgs {
+		if missing := s.findMissingRequiredArgument(toolName, request); missing != "" {
+			log.Printf("[EXT-PROC] ❌ Tool '%s' forwarded without required argument '%s'", toolName, missing)
+			return 0, fmt.Errorf("Missing required argument '%s' for tool %s", missing, toolName)
+		}
+	}
+
+	timeout, _ := s.responseTimeout(toolName, target)
+	return timeout, nil
+}
+
+// CheckForwardedToolResult validates the result of a tool call the helper forwarded itself against the
+// tool's output schema. A mismatch is logged, and returned as an error under reject.
+func (s *Server) CheckForwardedToolResult(helperSession, toolName, target string, result []byte) error {
+	if s == nil || s.config.OutputSchemaValidation == OutputSchemaOff {
+		return nil
+	}
+
+	decoded, ok := decodeJSON(result)
+	object, isObject := decoded.(map[string]any)
+	if !ok || !isObject {
+		return nil
+	}
+	if err := s.toolResultMismatch(target, toolName, helperSession, object); err != nil && s.config.OutputSchemaValidation == OutputSchemaReject {
+		return err
+	}
+	return nil
+}
+
+// RecordForwardedFailure records a tool call the helper could not forward in the dead-letter log,
+// as routeFailure does for tool calls ext-proc can't route
+func (s *Server) RecordForwardedFailure(helperSession string, request map[string]any, reason string, status int32) {
+	if s == nil || s.config.DeadLetters == nil {
+		return
+	}
+	s.config.DeadLetters.record(reason, status, false, helperSession, request)
+}
@@ -0,0 +1,107 @@
+package handlers
+
+import (
+	"testing"
+	"time"
+)
+
+// schemaHelper is a fakeHelper that knows the input and output schemas of its tools
+type schemaHelper struct {
+	*fakeHelper
+	required      map[string][]string
+	readOnly      map[string]bool
+	outputSchemas map[string]map[string]any
+}
+
+func (h *schemaHelper) GetToolRequiredArguments(toolName string) ([]string, bool) {
+	required, ok := h.required[toolName]
+	return required, ok
+}
+
+func (h *schemaHelper) IsToolReadOnly(toolName string) bool { return h.readOnly[toolName] }
+
+func (h *schemaHelper) GetToolOutputSchema(toolName string) (map[string]any, bool) {
+	schema, ok := h.outputSchemas[toolName]
+	return schema, ok
+}
+
+func newSchemaHelper() *schemaHelper {
+	return &schemaHelper{
+		fakeHelper: newFakeHelper("helper-1", map[string]string{"server1": "backend-1"}),
+		required:   map[string][]string{"server1-echo": {"text"}},
+		outputSchemas: map[string]map[string]any{"server1-echo": {
+			"type":       "object",
+			"properties": map[string]any{"text": map[string]any{"type": "string"}},
+			"required":   []any{"text"},
+		}},
+	}
+}
+
+// forwardedCall is a helper_call style request for server1-echo with the given arguments
+func forwardedCall(arguments map[string]any) map[string]any {
+	return map[string]any{
+		"jsonrpc": "2.0",
+		"method":  "tools/call",
+		"params":  map[string]any{"name": "server1-echo", "arguments": arguments},
+	}
+}
+
+func TestCheckForwardedToolCall(t *testing.T) {
+	s := NewServer(false, newSchemaHelper(), Config{
+		ValidateRequiredArgs:    true,
+		RateLimit:               0.001,
+		RateLimitBurst:          1,
+		BackendResponseTimeouts: map[string]time.Duration{"server1": 30 * time.Second},
+	})
+
+	if _, err := s.CheckForwardedToolCall("helper-1", "alice", "server1-echo", "server1", forwardedCall(map[string]any{})); err == nil {
+		t.Error("call without the required argument was allowed")
+	}
+
+	timeout, err := s.CheckForwardedToolCall("helper-2", "bob", "server1-echo", "server1", forwardedCall(map[string]any{"text": "hi"}))
+	if err != nil {
+		t.Fatalf("CheckForwardedToolCall() error = %v", err)
+	}
+	if timeout != 30*time.Second {
+		t.Errorf("timeout = %s, want the backend's 30s", timeout)
+	}
+
+	if _, err := s.CheckForwardedToolCall("helper-3", "bob", "server1-echo", "server1", forwardedCall(map[string]any{"text": "hi"})); err == nil {
+		t.Error("call over the principal's rate limit was allowed")
+	}
+}
+
+func TestCheckForwardedToolResult(t *testing.T) {
+	tests := []struct {
+		name    string
+		mode    OutputSchemaValidation
+		result  string
+		wantErr bool
+	}{
+		{name: "matching result", mode: OutputSchemaReject, result: `{"content":[],"structuredContent":{"text":"hi"}}`},
+		{name: "mismatch under reject", mode: OutputSchemaReject, result: `{"content":[],"structuredContent":{"text":1}}`, wantErr: true},
+		{name: "missing structured content under reject", mode: OutputSchemaReject, result: `{"content":[]}`, wantErr: true},
+		{name: "tool error under reject", mode: OutputSchemaReject, result: `{"content":[],"isError":true}`},
+		{name: "mismatch under log", mode: OutputSchemaLog, result: `{"content":[],"structuredContent":{"text":1}}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := NewServer(false, newSchemaHelper(), Config{OutputSchemaValidation: tt.mode})
+			err := s.CheckForwardedToolResult("helper-1", "server1-echo", "server1", []byte(tt.result))
+			if (err != nil) != tt.wantErr {
+				t.Errorf("CheckForwardedToolResult() error = %v, wantErr %t", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestForwardedPoliciesOnNilServer(t *testing.T) {
+	var s *Server
+	if timeout, err := s.CheckForwardedToolCall("helper-1", "", "server1-echo", "server1", forwardedCall(nil)); timeout != 0 || err != nil {
+		t.Errorf("CheckForwardedToolCall() = (%s, %v), want no policies", timeout, err)
+	}
+	if err := s.CheckForwardedToolResult("helper-1", "server1-echo", "server1", []byte(`{}`)); err != nil {
+		t.Errorf("CheckForwardedToolResult() = %v, want no policies", err)
+	}
+	s.RecordForwardedFailure("helper-1", forwardedCall(nil), "Unknown tool", 404)
+}
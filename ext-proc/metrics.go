@@ -0,0 +1,40 @@
+package handlers
+
+import (
+	"strconv"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// Routing metrics, registered with the default Prometheus registry
+var (
+	toolCallsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "mcp_helper_tool_calls_total",
+		Help: "Tool calls ext-proc resolved to a backend, by backend, tool, body processing mode (streaming or buffered) and whether the call went to a canary.",
+	}, []string{"backend", "tool", "mode", "canary"})
+
+	sessionMappingMissesTotal = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "mcp_helper_session_mapping_misses_total",
+		Help: "Tool calls whose helper session had no backend session mapping.",
+	})
+)
+
+// recordToolCall counts a routed tool call on the target it was sent to. Names not in the aggregated
+// tool set are counted as "unknown" so clients can't grow the label set without bound.
+func (s *Server) recordToolCall(routeTarget, toolName string, canary bool) {
+	if lookup, ok := s.helper.(ToolSchemaLookup); ok {
+		if _, known := lookup.GetToolRequiredArguments(toolName); !known {
+			toolName = "unknown"
+		}
+	}
+	toolCallsTotal.WithLabelValues(routeTarget, toolName, s.processingMode(), strconv.FormatBool(canary)).Inc()
+}
+
+// processingMode names the body processing mode this Server was configured with, for metric labels
+func (s *Server) processingMode() string {
+	if s.streaming {
+		return "streaming"
+	}
+	return "buffered"
+}
@@ -0,0 +1,110 @@
+package handlers
+
+import (
+	"context"
+	"log"
+	"strings"
+
+	eppb "github.com/envoyproxy/go-control-plane/envoy/service/ext_proc/v3"
+	typepb "github.com/envoyproxy/go-control-plane/envoy/type/v3"
+)
+
+// UnknownNotificationPolicy controls what happens to client notifications the helper doesn't handle
+type UnknownNotificationPolicy string
+
+const (
+	// NotificationDrop accepts unknown notifications and discards them
+	NotificationDrop UnknownNotificationPolicy = "drop"
+	// NotificationBroadcast forwards unknown notifications to every backend session of the client
+	NotificationBroadcast UnknownNotificationPolicy = "broadcast"
+	// NotificationError rejects unknown notifications with a 400
+	NotificationError UnknownNotificationPolicy = "error"
+)
+
+// ParseUnknownNotificationPolicy parses a notification policy, defaulting to drop for unknown values
+func ParseUnknownNotificationPolicy(policy string) UnknownNotificationPolicy {
+	switch UnknownNotificationPolicy(strings.ToLower(policy)) {
+	case NotificationDrop:
+		return NotificationDrop
+	case NotificationBroadcast:
+		return NotificationBroadcast
+	case NotificationError:
+		return NotificationError
+	default:
+		log.Printf("[EXT-PROC] ⚠️ Unknown notification policy %q, defaulting to %s", policy, NotificationDrop)
+		return NotificationDrop
+	}
+}
+
+// NotificationBroadcaster fans a client notification out to the client's backend sessions.
+// It is optional - the broadcast policy needs a SessionMapper that also implements it.
+type NotificationBroadcaster interface {
+	BroadcastNotification(ctx context.Context, helperSessionID, method string, params map[string]any) error
+}
+
+// Notifications the helper itself handles; these always continue to the helper
+var knownNotifications = map[string]bool{
+	"notifications/initialized":        true,
+	"notifications/cancelled":          true,
+	"notifications/progress":           true,
+	"notifications/roots/list_changed": true,
+}
+
+// isUnknownNotification reports whether a message is a notification the helper doesn't handle
+func isUnknownNotification(data map[string]any) bool {
+	if _, hasID := data["id"]; hasID {
+		return false
+	}
+	method := extractMCPMethod(data)
+	return method != "" && !knownNotifications[method]
+}
+
+// handleUnknownNotification applies the unknown notification policy
+func (s *Server) handleUnknownNotification(ctx context.Context, data map[string]any) []*eppb.ProcessingResponse {
+	method := extractMCPMethod(data)
+
+	switch s.config.UnknownNotifications {
+	case NotificationError:
+		log.Printf("[EXT-PROC] ❌ Rejecting unknown notification %s", method)
+		return s.createErrorResponse("Unsupported notification: "+method, 400)
+
+	case NotificationBroadcast:
+		broadcaster, ok := s.helper.(NotificationBroadcaster)
+		if !ok {
+			log.Printf("[EXT-PROC] ⚠️ Helper can't broadcast notifications, dropping %s", method)
+			break
+		}
+		helperSession := s.extractSessionFromContext(ctx)
+		if helperSession == "" {
+			log.Printf("[EXT-PROC] ⚠️ No session to broadcast notification %s on, dropping", method)
+			break
+		}
+		params, _ := data["params"].(map[string]any)
+		if err := broadcaster.BroadcastNotification(ctx, helperSession, method, params); err != nil {
+			log.Printf("[EXT-PROC] ⚠️ Failed to broadcast notification %s for %s: %v", method, helperSession, err)
+		} else {
+			log.Printf("[EXT-PROC] 📢 Broadcast notification %s to backends of %s", method, helperSession)
+		}
+
+	default:
+		s.debugf("[EXT-PROC] Dropping unknown notification %s", method)
+	}
+
+	return s.createAcceptedResponse()
+}
+
+// createAcceptedResponse answers a notification with 202 Accepted, as the MCP HTTP transport does
+func (s *Server) createAcceptedResponse() []*eppb.ProcessingResponse {
+	return []*eppb.ProcessingResponse{
+		{
+			Response: &eppb.ProcessingResponse_ImmediateResponse{
+				ImmediateResponse: &eppb.ImmediateResponse{
+					Status: &typepb.HttpStatus{
+						Code: typepb.StatusCode_Accepted,
+					},
+					Details: "notification accepted by ext-proc",
+				},
+			},
+		},
+	}
+}
@@ -0,0 +1,230 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"maps"
+	"slices"
+	"strings"
+
+	eppb "github.com/envoyproxy/go-control-plane/envoy/service/ext_proc/v3"
+)
+
+// OutputSchemaValidation controls what happens to tool results that don't match the tool's output schema
+type OutputSchemaValidation string
+
+const (
+	// OutputSchemaOff passes tool results through unchecked
+	OutputSchemaOff OutputSchemaValidation = "off"
+	// OutputSchemaLog logs mismatched results and passes them through
+	OutputSchemaLog OutputSchemaValidation = "log"
+	// OutputSchemaReject replaces mismatched results with a JSON-RPC error
+	OutputSchemaReject OutputSchemaValidation = "reject"
+)
+
+// ParseOutputSchemaValidation parses an output schema validation mode, defaulting to off for unknown values
+func ParseOutputSchemaValidation(mode string) OutputSchemaValidation {
+	switch OutputSchemaValidation(strings.ToLower(mode)) {
+	case OutputSchemaOff:
+		return OutputSchemaOff
+	case OutputSchemaLog:
+		return OutputSchemaLog
+	case OutputSchemaReject:
+		return OutputSchemaReject
+	default:
+		log.Printf("[EXT-PROC] ⚠️ Unknown output schema validation %q, defaulting to %s", mode, OutputSchemaOff)
+		return OutputSchemaOff
+	}
+}
+
+// ToolOutputSchemas gives ext-proc the output schemas backends declare for their tools.
+// It is optional - a SessionMapper that also implements it enables tool result validation.
+type ToolOutputSchemas interface {
+	GetToolOutputSchema(toolName string) (map[string]any, bool)
+}
+
+// checkToolResult validates the result of a routed tool call against the tool's output schema.
+// A mismatch is logged and keeps the result out of the cache; under reject the client gets a
+// JSON-RPC error instead of the result.
+func (s *Server) checkToolResult(route *routeState, body []byte) []*eppb.ProcessingResponse {
+	if s.config.OutputSchemaValidation == OutputSchemaOff || route == nil || !route.toolCall {
+		return nil
+	}
+
+	decoded, ok := decodeJSON(jsonRPCPayload(body))
+	response, isObject := decoded.(map[string]any)
+	if !ok || !isObject {
+		return nil
+	}
+	result, ok := response["result"].(map[string]any)
+	if !ok {
+		return nil
+	}
+
+	err := s.toolResultMismatch(route.target, route.name, route.helperSession, result)
+	if err == nil {
+		return nil
+	}
+	route.cacheKey = ""
+
+	if s.config.OutputSchemaValidation != OutputSchemaReject {
+		return nil
+	}
+	return s.createJSONRPCErrorResponse(response["id"], outputSchemaMismatchCode, err.Error())
+}
+
+// toolResultMismatch checks a decoded tool result against the tool's output schema, logging and
+// returning the mismatch. Tools without a declared schema always match.
+func (s *Server) toolResultMismatch(target, toolName, helperSession string, result map[string]any) error {
+	lookup, ok := s.helper.(ToolOutputSchemas)
+	if !ok {
+		return nil
+	}
+	schema, ok := lookup.GetToolOutputSchema(toolName)
+	if !ok {
+		return nil
+	}
+	// Tool-level failures report the error as content and carry no structured result
+	if isError, _ := result["isError"].(bool); isError {
+		return nil
+	}
+
+	err := errors.New("result has no structuredContent")
+	if structured, present := result["structuredContent"]; present {
+		err = validateSchema(schema, structured, "structuredContent")
+	}
+	if err == nil {
+		return nil
+	}
+
+	logger().Warn("tool result does not match output schema",
+		"backend", target,
+		"tool", toolName,
+		"session_id", helperSession,
+		"error", err)
+	return fmt.Errorf("Tool %s returned a result that does not match its output schema: %v", toolName, err)
+}
+
+// validateSchema checks a value against the JSON Schema keywords tools use to describe their output:
+// type, enum, const, properties, required, additionalProperties and items. Other keywords are ignored.
+func validateSchema(schema map[string]any, value any, path string) error {
+	if types, ok := schema["type"]; ok && !matchesType(types, value) {
+		return fmt.Errorf("%s: expected %s, got %s", path, describeTypes(types), jsonType(value))
+	}
+	if enum, ok := schema["enum"].([]any); ok && !slices.ContainsFunc(enum, func(allowed any) bool { return sameJSON(allowed, value) }) {
+		return fmt.Errorf("%s: value is not one of the allowed values", path)
+	}
+	if constant, ok := schema["const"]; ok && !sameJSON(constant, value) {
+		return fmt.Errorf("%s: value does not equal the required constant", path)
+	}
+
+	switch value := value.(type) {
+	case map[string]any:
+		required, _ := schema["required"].([]any)
+		for _, name := range required {
+			if name, ok := name.(string); ok {
+				if _, present := value[name]; !present {
+					return fmt.Errorf("%s: missing required property %q", path, name)
+				}
+			}
+		}
+
+		properties, _ := schema["properties"].(map[string]any)
+		for _, name := range slices.Sorted(maps.Keys(value)) {
+			propertyPath := path + "." + name
+			if property, ok := properties[name].(map[string]any); ok {
+				if err := validateSchema(property, value[name], propertyPath); err != nil {
+					return err
+				}
+				continue
+			}
+			if _, declared := properties[name]; declared {
+				continue
+			}
+			switch additional := schema["additionalProperties"].(type) {
+			case bool:
+				if !additional {
+					return fmt.Errorf("%s: unexpected property", propertyPath)
+				}
+			case map[string]any:
+				if err := validateSchema(additional, value[name], propertyPath); err != nil {
+					return err
+				}
+			}
+		}
+	case []any:
+		if items, ok := schema["items"].(map[string]any); ok {
+			for i, item := range value {
+				if err := validateSchema(items, item, fmt.Sprintf("%s[%d]", path, i)); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// matchesType reports whether a value has the schema type, or one of the types when it is a list
+func matchesType(types any, value any) bool {
+	actual := jsonType(value)
+	matches := func(expected any) bool {
+		return expected == actual || (expected == "number" && actual == "integer")
+	}
+	if list, ok := types.([]any); ok {
+		return slices.ContainsFunc(list, matches)
+	}
+	return matches(types)
+}
+
+// describeTypes renders a schema type, or list of types, for error messages
+func describeTypes(types any) string {
+	if list, ok := types.([]any); ok {
+		names := make([]string, 0, len(list))
+		for _, name := range list {
+			names = append(names, fmt.Sprint(name))
+		}
+		return strings.Join(names, " or ")
+	}
+	return fmt.Sprint(types)
+}
+
+// jsonType returns the JSON Schema type of a decoded JSON value
+func jsonType(value any) string {
+	switch value := value.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case string:
+		return "string"
+	case json.Number:
+		if _, err := value.Int64(); err == nil {
+			return "integer"
+		}
+		if f, err := value.Float64(); err == nil && f == float64(int64(f)) {
+			return "integer"
+		}
+		return "number"
+	case float64:
+		if value == float64(int64(value)) {
+			return "integer"
+		}
+		return "number"
+	case map[string]any:
+		return "object"
+	case []any:
+		return "array"
+	default:
+		return fmt.Sprintf("%T", value)
+	}
+}
+
+// sameJSON reports whether two decoded JSON values are equal, comparing their canonical encodings
+func sameJSON(a, b any) bool {
+	encodedA, errA := CanonicalJSON(a)
+	encodedB, errB := CanonicalJSON(b)
+	return errA == nil && errB == nil && bytes.Equal(encodedA, encodedB)
+}
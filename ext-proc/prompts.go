@@ -0,0 +1,43 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	eppb "github.com/envoyproxy/go-control-plane/envoy/service/ext_proc/v3"
+)
+
+// extractMCPPromptName safely extracts the prompt name from an MCP prompts/get request
+func extractMCPPromptName(data map[string]any) string {
+	if extractMCPMethod(data) != "prompts/get" {
+		return ""
+	}
+
+	params, ok := data["params"].(map[string]any)
+	if !ok {
+		log.Println("[EXT-PROC] MCP prompt get params is not an object")
+		return ""
+	}
+	name, ok := params["name"].(string)
+	if !ok {
+		log.Println("[EXT-PROC] MCP prompt get missing name in params")
+		return ""
+	}
+	return name
+}
+
+// handlePromptGet routes a prompts/get request to the backend owning the prompt, stripping the
+// group namespace or prefix from its name as stripServerPrefix does for tools
+func (s *Server) handlePromptGet(ctx context.Context, data map[string]any, promptName string, route *routeState) []*eppb.ProcessingResponse {
+	s.debugf("[EXT-PROC] Prompt name: %s", promptName)
+
+	routeTarget, backendPromptName, ok := StripToolPrefix(promptName)
+	if !ok {
+		log.Printf("[EXT-PROC] Prompt name '%s' doesn't match any server prefix", promptName)
+		return s.routeFailure(ctx, data, fmt.Sprintf("Unknown prompt: %s", promptName), 404)
+	}
+
+	return s.routeRenamedRequest(ctx, data, route, "name", promptName, routeTarget, backendPromptName,
+		routingEvent{Prompt: promptName, StrippedPrompt: backendPromptName})
+}
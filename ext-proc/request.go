@@ -5,7 +5,9 @@ import (
 	"encoding/json"
 	"fmt"
 	"log"
+	"log/slog"
 	"strings"
+	"time"
 
 	basepb "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
 	eppb "github.com/envoyproxy/go-control-plane/envoy/service/ext_proc/v3"
@@ -13,13 +15,58 @@ import (
 )
 
 const (
-	toolHeader    = "x-mcp-toolname"
-	serverHeader  = "x-mcp-server"
-	sessionHeader = "mcp-session-id"
+	toolHeader   = "x-mcp-toolname"
+	serverHeader = "x-mcp-server"
+
+	// Opt-in header carrying the aggregated name the client asked for, next to the stripped name in the body
+	originalToolHeader = "x-mcp-original-toolname"
+
+	// Session header of the MCP streamable HTTP transport, used by backends unless configured otherwise
+	defaultSessionHeader = "mcp-session-id"
+
+	// JSON-encoded request ID, used by Envoy's local reply to build JSON-RPC timeout errors
+	jsonrpcIDHeader = "x-mcp-jsonrpc-id"
+	// Per-request upstream timeout honoured by the Envoy router
+	upstreamTimeoutHeader = "x-envoy-upstream-rq-timeout-ms"
+	// Time from routing to the end of the backend response, when enabled
+	backendDurationHeader = "x-mcp-backend-duration-ms"
+
+	// Tools served by the helper itself carry this prefix and are never routed
+	helperToolPrefix = "helper_"
+
+	// JSON-RPC invalid params error code
+	invalidParamsCode = -32602
+	// JSON-RPC server error code for requests to a backend in maintenance
+	backendMaintenanceCode = -32002
+	// JSON-RPC server error code for requests to a backend the helper started without
+	backendUnavailableCode = -32003
+	// JSON-RPC server error code for tool results that don't match the tool's output schema
+	outputSchemaMismatchCode = -32004
 )
 
-// extractMCPToolName safely extracts the tool name from MCP tool call request
-func extractMCPToolName(data map[string]any) string {
+// sessionHeader carries the helper session between clients, the gateway and the helper.
+// It is the standard MCP header unless changed with SetClientSessionHeader.
+var sessionHeader = defaultSessionHeader
+
+// SetClientSessionHeader overrides the header carrying the helper session, for gateways that use
+// their own session header. It must be called before serving; backends are unaffected.
+func SetClientSessionHeader(header string) {
+	if header == "" {
+		return
+	}
+	sessionHeader = strings.ToLower(header)
+	if sessionHeader != defaultSessionHeader {
+		log.Printf("[EXT-PROC] Using session header %s for helper sessions", sessionHeader)
+	}
+}
+
+// ClientSessionHeader returns the header carrying the helper session
+func ClientSessionHeader() string {
+	return sessionHeader
+}
+
+// extractMCPMethod safely extracts the JSON-RPC method from an MCP request
+func extractMCPMethod(data map[string]any) string {
 	// Check if this is a JSON-RPC request
 	jsonrpcVal, ok := data["jsonrpc"]
 	if !ok {
@@ -31,7 +78,6 @@ func extractMCPToolName(data map[string]any) string {
 		return ""
 	}
 
-	// Extract method field and check if it's tools/call
 	methodVal, ok := data["method"]
 	if !ok {
 		return ""
@@ -42,7 +88,31 @@ func extractMCPToolName(data map[string]any) string {
 		return ""
 	}
 
-	if methodStr != "tools/call" {
+	return methodStr
+}
+
+// normalizeJSONRPCVersion treats a request that carries a method but omits or misstates the jsonrpc
+// version as JSON-RPC 2.0, so requests from lenient clients are routed rather than falling through
+func normalizeJSONRPCVersion(data map[string]any) {
+	if version, ok := data["jsonrpc"].(string); ok && version == "2.0" {
+		return
+	}
+	method, ok := data["method"].(string)
+	if !ok || method == "" {
+		return
+	}
+	version, present := data["jsonrpc"]
+	if !present {
+		version = "(missing)"
+	}
+	log.Printf("[EXT-PROC] 🩹 Lenient parse: treating %s request with jsonrpc %v as 2.0", method, version)
+	data["jsonrpc"] = "2.0"
+}
+
+// extractMCPToolName safely extracts the tool name from MCP tool call request
+func extractMCPToolName(data map[string]any) string {
+	// Only tools/call requests carry a routable tool name
+	if extractMCPMethod(data) != "tools/call" {
 		return ""
 	}
 
@@ -75,48 +145,169 @@ func extractMCPToolName(data map[string]any) string {
 	return nameStr
 }
 
-// Server configuration for tool processing
-var serverConfigs = []struct {
-	prefix string
-	target string
-}{{
-	prefix: "server1-",
-	target: "server1",
-}, {
-	prefix: "server2-",
-	target: "server2",
-}}
+// serverConfig describes how tools are routed to one backend target
+type serverConfig struct {
+	prefix        string
+	group         string // "<group><separator>" namespace, used instead of prefix when set
+	target        string
+	sessionHeader string // header the backend uses to carry its session ID
+	logBodies     bool   // log routed request and response bodies, redacted
+}
+
+// Backend targets for tool processing, registered with AddBackend at startup
+var serverConfigs []serverConfig
 
-// getRouteTargetFromTool determines which server to route to based on tool name prefix
-func getRouteTargetFromTool(toolName string) string {
+// AddBackend registers a backend target whose tools carry the given prefix.
+// Registering a target again replaces its prefix.
+func AddBackend(target, prefix string) {
+	for i := range serverConfigs {
+		if serverConfigs[i].target == target {
+			serverConfigs[i].prefix = prefix
+			return
+		}
+	}
+	serverConfigs = append(serverConfigs, serverConfig{
+		prefix:        prefix,
+		target:        target,
+		sessionHeader: defaultSessionHeader,
+	})
+}
+
+// ToolPrefix returns the prefix the helper adds to tool names from a backend target during
+// aggregation - its group namespace when one is set; stripServerPrefix removes the same prefix,
+// so the two must stay in sync
+func ToolPrefix(target string) string {
 	for _, config := range serverConfigs {
-		if strings.HasPrefix(toolName, config.prefix) {
-			return config.target
+		if config.target == target {
+			if config.group != "" {
+				return config.group
+			}
+			return config.prefix
 		}
 	}
-	return ""
+	return target + "-"
 }
 
-// stripServerPrefix removes serverN- prefix from tool names
+// SetToolGroup namespaces a backend target's tools as "<group><separator><tool>" instead of
+// the flat prefix. Calls using the flat prefix are still routed, so existing clients keep working.
+func SetToolGroup(target, group, separator string) error {
+	if group == "" {
+		return nil
+	}
+	if separator == "" || separator == "-" {
+		return fmt.Errorf("tool group separator %q must be set and differ from the %q prefix separator", separator, "-")
+	}
+	if strings.Contains(group, separator) {
+		return fmt.Errorf("tool group %q for %s must not contain the separator %q", group, target, separator)
+	}
+	for i := range serverConfigs {
+		if serverConfigs[i].target == target {
+			serverConfigs[i].group = group + separator
+			log.Printf("[EXT-PROC] Grouping %s tools under %s", target, serverConfigs[i].group)
+		}
+	}
+	return nil
+}
+
+// SetSessionHeader overrides the session header name used by a backend target,
+// for backends that don't use the standard mcp-session-id header
+func SetSessionHeader(target, header string) {
+	if header == "" {
+		return
+	}
+	for i := range serverConfigs {
+		if serverConfigs[i].target == target {
+			serverConfigs[i].sessionHeader = strings.ToLower(header)
+			log.Printf("[EXT-PROC] Using session header %s for %s", serverConfigs[i].sessionHeader, target)
+		}
+	}
+}
+
+// getSessionHeaderForTarget returns the session header name used by a backend target
+func getSessionHeaderForTarget(target string) string {
+	for _, config := range serverConfigs {
+		if config.target == target && config.sessionHeader != "" {
+			return config.sessionHeader
+		}
+	}
+	return defaultSessionHeader
+}
+
+// matchServerConfig finds the backend a tool name belongs to, returning the namespace to strip.
+// Group namespaces win over flat prefixes, and the longest match wins so one prefix may extend another.
+func matchServerConfig(toolName string) (serverConfig, string, bool) {
+	var match serverConfig
+	var namespace string
+	for _, grouped := range []bool{true, false} {
+		for _, config := range serverConfigs {
+			candidate := config.prefix
+			if grouped {
+				candidate = config.group
+			}
+			if candidate != "" && strings.HasPrefix(toolName, candidate) && len(candidate) > len(namespace) {
+				match, namespace = config, candidate
+			}
+		}
+		if namespace != "" {
+			return match, namespace, true
+		}
+	}
+	return serverConfig{}, "", false
+}
+
+// getRouteTargetFromTool determines which server to route to based on tool name group or prefix
+func getRouteTargetFromTool(toolName string) string {
+	config, _, ok := matchServerConfig(toolName)
+	if !ok {
+		return ""
+	}
+	return config.target
+}
+
+// stripServerPrefix removes the group namespace or backend prefix from tool names
 // Returns the stripped name and whether stripping was needed
 func stripServerPrefix(toolName string) (string, bool) {
-	for _, config := range serverConfigs {
-		if strings.HasPrefix(toolName, config.prefix) {
-			return strings.TrimPrefix(toolName, config.prefix), true
+	_, namespace, ok := matchServerConfig(toolName)
+	if !ok {
+		return toolName, false
+	}
+	return strings.TrimPrefix(toolName, namespace), true
+}
+
+// StripToolPrefix returns the backend target and backend tool name for an aggregated tool name,
+// accepting both group-namespaced and flat-prefixed names
+func StripToolPrefix(toolName string) (target, backendToolName string, ok bool) {
+	target = getRouteTargetFromTool(toolName)
+	if target == "" {
+		return "", toolName, false
+	}
+	backendToolName, _ = stripServerPrefix(toolName)
+	return target, backendToolName, true
+}
+
+// resolveToolRoute returns the backend target and backend tool name for a tool call. Tools the helper
+// aggregated are looked up exactly, so overlapping prefixes and tool names that themselves start with a
+// prefix route to the backend that listed them. Other names, such as flat-prefixed aliases of grouped
+// tools or calls made before discovery, fall back to prefix matching.
+func (s *Server) resolveToolRoute(toolName string) (target, backendToolName string, ok bool) {
+	if routes, isRoutes := s.helper.(ToolRoutes); isRoutes {
+		if target, backendToolName, ok := routes.ToolRoute(toolName); ok {
+			return target, backendToolName, true
 		}
 	}
-	return toolName, false
+	return StripToolPrefix(toolName)
 }
 
-// extractSessionFromContext extracts mcp-session-id from the stored request headers
+// extractSessionFromContext extracts the helper session from the request headers stored on the stream context
 func (s *Server) extractSessionFromContext(ctx context.Context) string {
-	if s.requestHeaders == nil || s.requestHeaders.Headers == nil {
+	requestHeaders, ok := ctx.Value(requestHeadersKey{}).(*eppb.HttpHeaders)
+	if !ok || requestHeaders == nil || requestHeaders.Headers == nil {
 		return ""
 	}
 
-	// Extract mcp-session-id from stored headers
-	for _, header := range s.requestHeaders.Headers.Headers {
-		if strings.ToLower(header.Key) == "mcp-session-id" {
+	// Extract the helper session from stored headers
+	for _, header := range requestHeaders.Headers.Headers {
+		if strings.ToLower(header.Key) == sessionHeader {
 			return string(header.RawValue)
 		}
 	}
@@ -125,30 +316,99 @@ func (s *Server) extractSessionFromContext(ctx context.Context) string {
 }
 
 // HandleRequestBody handles request bodies for MCP tool calls.
-func (s *Server) HandleRequestBody(ctx context.Context, data map[string]any) ([]*eppb.ProcessingResponse, error) {
-	log.Println("[EXT-PROC] Processing request body for MCP tool calls...")
+// The routing decision is recorded in route so the response phase can use it.
+func (s *Server) HandleRequestBody(ctx context.Context, data map[string]any, route *routeState) ([]*eppb.ProcessingResponse, error) {
+	s.debugf("[EXT-PROC] Processing request body for MCP tool calls...")
+
+	// Lenient clients omit or misstate the jsonrpc version, strict mode leaves their requests to the helper
+	if s.config.LenientJSONRPC {
+		normalizeJSONRPCVersion(data)
+	}
+
+	// ping is answered by the helper itself and must never be routed to a backend
+	if extractMCPMethod(data) == "ping" {
+		s.debugf("[EXT-PROC] 🏓 ping request, continuing to helper")
+		return s.createEmptyBodyResponse(), nil
+	}
+
+	// logging/setLevel is relayed to every backend by the helper on the client's backend sessions
+	if extractMCPMethod(data) == "logging/setLevel" {
+		s.debugf("[EXT-PROC] logging/setLevel request, continuing to helper for relay")
+		return s.createEmptyBodyResponse(), nil
+	}
+
+	// Notifications the helper doesn't understand are dropped, broadcast or rejected by policy
+	if isUnknownNotification(data) {
+		return s.handleUnknownNotification(ctx, data), nil
+	}
+
+	// resources/read is routed by its URI prefix, the same way tools/call is by tool name
+	if uri := extractMCPResourceURI(data); uri != "" {
+		return s.handleResourceRead(ctx, data, uri, route), nil
+	}
+
+	// prompts/get is routed by prompt name, which carries the same prefix as the backend's tools
+	if promptName := extractMCPPromptName(data); promptName != "" {
+		return s.handlePromptGet(ctx, data, promptName, route), nil
+	}
 
 	// Extract tool name - only process tools/call
 	toolName := extractMCPToolName(data)
 	if toolName == "" {
-		log.Println("[EXT-PROC] No MCP tool name found or not tools/call, continuing to helper")
+		s.debugf("[EXT-PROC] No MCP tool name found or not tools/call, continuing to helper")
 		return s.createEmptyBodyResponse(), nil
 	}
 
-	log.Printf("[EXT-PROC] Tool name: %s", toolName)
+	s.debugf("[EXT-PROC] Tool name: %s", toolName)
 
-	// Determine routing based on tool prefix
-	routeTarget := getRouteTargetFromTool(toolName)
-	if routeTarget == "" {
-		log.Printf("[EXT-PROC] Tool name '%s' doesn't match any server prefix, continuing to helper", toolName)
+	// The helper's own tools are handled locally
+	if strings.HasPrefix(toolName, helperToolPrefix) {
+		s.debugf("[EXT-PROC] Tool '%s' is a helper tool, continuing to helper", toolName)
 		return s.createEmptyBodyResponse(), nil
 	}
 
-	log.Printf("[EXT-PROC] Routing to: %s", routeTarget)
+	// Determine routing from the helper's tool table, or the tool prefix for names it doesn't list
+	routeTarget, strippedToolName, ok := s.resolveToolRoute(toolName)
+	if !ok {
+		log.Printf("[EXT-PROC] Tool name '%s' doesn't match any server prefix", toolName)
+		return s.routeFailure(ctx, data, fmt.Sprintf("Unknown tool: %s", toolName), 404), nil
+	}
 
-	// Strip server prefix from tool name and modify request body
-	strippedToolName, _ := stripServerPrefix(toolName)
-	log.Printf("[EXT-PROC] Stripped tool name: %s", strippedToolName)
+	s.debugf("[EXT-PROC] Routing to: %s", routeTarget)
+
+	// Schemas, read-only hints and visibility are recorded under the aggregated name, which a flat-prefixed
+	// alias of a grouped tool doesn't match
+	aggregatedName := ToolPrefix(routeTarget) + strippedToolName
+
+	helperSession, sessionMapping, failure := s.routingSession(ctx, data)
+	if failure != nil {
+		return failure, nil
+	}
+
+	// Hidden tools look exactly like unknown ones to principals who can't see them, so this is checked
+	// before any response or metric that would reveal the tool. The aggregated name is checked too, so a
+	// flat-prefixed alias can't reach a hidden grouped tool.
+	if visibility, ok := s.helper.(ToolVisibility); ok {
+		if !visibility.IsToolVisible(toolName, sessionMapping.Principal) || !visibility.IsToolVisible(aggregatedName, sessionMapping.Principal) {
+			log.Printf("[EXT-PROC] ❌ Tool '%s' (%s) is not visible to principal '%s'", toolName, aggregatedName, sessionMapping.Principal)
+			return s.createJSONRPCErrorResponse(data["id"], invalidParamsCode, fmt.Sprintf("Unknown tool: %s", toolName)), nil
+		}
+	}
+
+	if response := s.maintenanceResponse(data, routeTarget); response != nil {
+		return response, nil
+	}
+
+	// Cheap pre-flight check for the most common client bug - a missing required argument
+	if s.config.ValidateRequiredArgs {
+		if missing := s.findMissingRequiredArgument(aggregatedName, data); missing != "" {
+			log.Printf("[EXT-PROC] ❌ Tool '%s' called without required argument '%s'", toolName, missing)
+			return s.createJSONRPCErrorResponse(data["id"], invalidParamsCode,
+				fmt.Sprintf("Missing required argument '%s' for tool %s", missing, toolName)), nil
+		}
+	}
+
+	s.debugf("[EXT-PROC] Stripped tool name: %s", strippedToolName)
 
 	// Create modified request body with stripped tool name
 	modifiedData := make(map[string]any)
@@ -158,58 +418,242 @@ func (s *Server) HandleRequestBody(ctx context.Context, data map[string]any) ([]
 
 	if params, ok := modifiedData["params"].(map[string]interface{}); ok {
 		params["name"] = strippedToolName
-		log.Printf("[EXT-PROC] ✅ Updated tool name in request body: %s", strippedToolName)
+		s.debugf("[EXT-PROC] ✅ Updated tool name in request body: %s", strippedToolName)
 	}
 
 	requestBodyBytes, err := json.Marshal(modifiedData)
 	if err != nil {
 		log.Printf("[EXT-PROC] Failed to marshal modified request body: %v", err)
-		return s.createEmptyBodyResponse(), nil
+		return s.routeFailure(ctx, data, "Failed to rewrite request body", 500), nil
+	}
+
+	if !s.allowCall(helperSession, sessionMapping.Principal) {
+		return s.createErrorResponse("Rate limit exceeded", 429), nil
+	}
+
+	// Use the correct backend session ID
+	backendSession := sessionMapping.BackendSessions[routeTarget]
+
+	s.debugf("[EXT-PROC] Using helper-provided session: %s", backendSession)
+
+	// Send a share of calls to a canary target; the canary reuses the stable backend session and
+	// its response timeout, so a canary can't be configured into a different deadline by accident
+	backendSessionHeader := getSessionHeaderForTarget(routeTarget)
+	timeout, _ := s.responseTimeout(aggregatedName, routeTarget)
+	routeTarget, isCanary := s.config.Canary.selectCanaryTarget(toolName, routeTarget, helperSession)
+	s.recordToolCall(routeTarget, aggregatedName, isCanary)
+
+	// Serve idempotent read-only tools from the cache when possible, never mixing in canary results
+	if key, ok := s.responseCacheKey(cacheScope(sessionMapping.Principal, helperSession), aggregatedName, routeTarget, strippedToolName, data); ok && !isCanary {
+		if result, hit := s.cache.get(key); hit {
+			log.Printf("[EXT-PROC] ⚡ Cache hit for %s on %s", strippedToolName, routeTarget)
+			return s.createJSONRPCResultResponse(data["id"], result), nil
+		}
+		if route != nil {
+			route.cacheKey = key
+		}
+	}
+
+	// Remember the routing decision for the response phase of this stream
+	if route != nil {
+		route.target = routeTarget
+		route.sessionHeader = backendSessionHeader
+		route.routedAt = time.Now()
+		route.name = aggregatedName
+		route.toolCall = true
+		route.helperSession = helperSession
 	}
 
+	s.logRoutingEvent(routingEvent{
+		Tool:           toolName,
+		StrippedTool:   strippedToolName,
+		Target:         routeTarget,
+		Canary:         isCanary,
+		HelperSession:  helperSession,
+		Principal:      sessionMapping.Principal,
+		BackendSession: backendSession,
+		Streaming:      s.streaming,
+		BodyBytes:      len(requestBodyBytes),
+	})
+	s.logRequestBody(routeTarget, toolName, helperSession, modifiedData)
+
+	return s.createRoutingResponse(toolName, requestBodyBytes, routeTarget, backendSession, backendSessionHeader, timeout, data["id"]), nil
+}
+
+// routingSession looks up the helper session of a request and its backend session mapping.
+// When the request can't be routed, the failure response to send is returned instead.
+func (s *Server) routingSession(ctx context.Context, data map[string]any) (string, *SessionMapping, []*eppb.ProcessingResponse) {
 	// Get Helper session ID
 	helperSession := s.extractSessionFromContext(ctx)
 	if helperSession == "" {
-		log.Println("[EXT-PROC] ❌ No mcp-session-id found in headers")
-		return s.createErrorResponse("No session ID found", 400), nil
+		log.Printf("[EXT-PROC] ❌ No %s found in headers", sessionHeader)
+		return "", nil, s.routeFailure(ctx, data, "No session ID found", 400)
 	}
 
-	log.Printf("[EXT-PROC] Helper session: %s", helperSession)
+	s.debugf("[EXT-PROC] Helper session: %s", helperSession)
 
 	// Lookup session mapping directly from helper
 	if s.helper == nil {
 		log.Println("[EXT-PROC] ❌ No helper available for session lookup")
-		return s.createErrorResponse("Helper not available", 500), nil
+		return "", nil, s.routeFailure(ctx, data, "Helper not available", 500)
 	}
 
 	sessionMapping, found := s.helper.GetSessionMapping(helperSession)
 	if !found {
-		log.Printf("[EXT-PROC] ❌ Session mapping not found for %s", helperSession)
+		logger().Warn("session mapping not found", "session_id", helperSession)
+		sessionMappingMissesTotal.Inc()
 
-		// Dump entire session store for debugging
-		log.Printf("[EXT-PROC] 🔍 Dumping session store for debugging:")
-		s.helper.DumpAllSessions()
+		// Only the number of live sessions is logged; their IDs are credentials
+		if logger().Enabled(ctx, slog.LevelDebug) {
+			logger().Debug("session store on mapping miss", "sessions", len(s.helper.DumpAllSessions()))
+		}
 
-		// Return 500 error instead of fallback
-		return s.createErrorResponse("Session mapping not found", 500), nil
+		// 404 tells MCP clients the session is gone and they should initialize a new one
+		return "", nil, s.routeFailure(ctx, data, "Session not found", 404)
+	}
+	if failure := s.authenticate(ctx, sessionMapping); failure != nil {
+		return "", nil, failure
 	}
+	return helperSession, sessionMapping, nil
+}
 
-	// Use the correct backend session ID
-	var backendSession string
-	if routeTarget == "server1" {
-		backendSession = sessionMapping.Server1SessionID
-	} else {
-		backendSession = sessionMapping.Server2SessionID
+// maintenanceResponse returns a friendly JSON-RPC error for a request to a backend in maintenance or
+// one the helper started without, or nil when the backend is serving
+func (s *Server) maintenanceResponse(data map[string]any, routeTarget string) []*eppb.ProcessingResponse {
+	if maintenance, ok := s.helper.(BackendMaintenance); ok && maintenance.IsBackendInMaintenance(routeTarget) {
+		log.Printf("[EXT-PROC] 🚧 %s is in maintenance, not routing", routeTarget)
+		return s.createJSONRPCErrorResponse(data["id"], backendMaintenanceCode,
+			fmt.Sprintf("Backend %s is temporarily unavailable for maintenance, try again later", routeTarget))
+	}
+	if availability, ok := s.helper.(BackendAvailability); ok {
+		if reason, unavailable := availability.BackendUnavailable(routeTarget); unavailable {
+			log.Printf("[EXT-PROC] ⛔ %s is unavailable, not routing: %s", routeTarget, reason)
+			return s.createJSONRPCErrorResponse(data["id"], backendUnavailableCode,
+				fmt.Sprintf("Backend %s is unavailable: it failed to initialize when the helper started", routeTarget))
+		}
 	}
+	return nil
+}
 
-	log.Printf("[EXT-PROC] Using helper-provided session: %s", backendSession)
+// allowCall applies the rate limit per principal - sessions are cheap to churn, identities are not
+func (s *Server) allowCall(helperSession, principal string) bool {
+	if s.rateLimiter == nil {
+		return true
+	}
+	rateKey := "session:" + helperSession
+	if principal != "" {
+		rateKey = "principal:" + principal
+	}
+	if !s.rateLimiter.Allow(rateKey) {
+		log.Printf("[EXT-PROC] 🚦 Rate limit exceeded for %s", rateKey)
+		return false
+	}
+	return true
+}
+
+// routeRenamedRequest routes a request other than tools/call to routeTarget, replacing the aggregated
+// name in params[key] with the backend's own name for it
+func (s *Server) routeRenamedRequest(ctx context.Context, data map[string]any, route *routeState, key, name, routeTarget, backendName string, event routingEvent) []*eppb.ProcessingResponse {
+	if response := s.maintenanceResponse(data, routeTarget); response != nil {
+		return response
+	}
+
+	modifiedData := make(map[string]any)
+	for k, v := range data {
+		modifiedData[k] = v
+	}
+	params := make(map[string]any)
+	if original, ok := data["params"].(map[string]any); ok {
+		for k, v := range original {
+			params[k] = v
+		}
+	}
+	params[key] = backendName
+	modifiedData["params"] = params
+
+	requestBodyBytes, err := json.Marshal(modifiedData)
+	if err != nil {
+		log.Printf("[EXT-PROC] Failed to marshal modified request body: %v", err)
+		return s.routeFailure(ctx, data, "Failed to rewrite request body", 500)
+	}
 
-	return s.createRoutingResponse(toolName, requestBodyBytes, routeTarget, backendSession), nil
+	helperSession, sessionMapping, failure := s.routingSession(ctx, data)
+	if failure != nil {
+		return failure
+	}
+	if !s.allowCall(helperSession, sessionMapping.Principal) {
+		return s.createErrorResponse("Rate limit exceeded", 429)
+	}
+
+	backendSession := sessionMapping.BackendSessions[routeTarget]
+	backendSessionHeader := getSessionHeaderForTarget(routeTarget)
+
+	if route != nil {
+		route.target = routeTarget
+		route.sessionHeader = backendSessionHeader
+		route.routedAt = time.Now()
+		route.name = name
+		route.helperSession = helperSession
+	}
+
+	event.Target = routeTarget
+	event.HelperSession = helperSession
+	event.Principal = sessionMapping.Principal
+	event.BackendSession = backendSession
+	event.Streaming = s.streaming
+	event.BodyBytes = len(requestBodyBytes)
+	s.logRoutingEvent(event)
+	s.logRequestBody(routeTarget, name, helperSession, modifiedData)
+
+	timeout, _ := s.responseTimeout(name, routeTarget)
+	return s.createRoutingResponse(name, requestBodyBytes, routeTarget, backendSession, backendSessionHeader, timeout, data["id"])
 }
 
-// createRoutingResponse creates a response with routing headers and session mapping
-func (s *Server) createRoutingResponse(toolName string, bodyBytes []byte, routeTarget, backendSession string) []*eppb.ProcessingResponse {
-	log.Printf("[EXT-PROC] 🔧 createRoutingResponse - streaming: %v, route: %s, session: %s", s.streaming, routeTarget, backendSession)
+// routingEvent is the single structured log event emitted for each routed request
+type routingEvent struct {
+	Tool             string
+	StrippedTool     string
+	Resource         string
+	StrippedResource string
+	Prompt           string
+	StrippedPrompt   string
+	Target           string
+	Canary           bool
+	HelperSession    string
+	Principal        string
+	BackendSession   string
+	Streaming        bool
+	BodyBytes        int
+}
+
+// logRoutingEvent logs a routing decision as one structured record at info level
+func (s *Server) logRoutingEvent(event routingEvent) {
+	attrs := []any{"backend", event.Target, "session_id", event.HelperSession}
+	for _, field := range []struct{ key, value string }{
+		{"tool", event.Tool},
+		{"stripped_tool", event.StrippedTool},
+		{"resource", event.Resource},
+		{"stripped_resource", event.StrippedResource},
+		{"prompt", event.Prompt},
+		{"stripped_prompt", event.StrippedPrompt},
+		{"principal", event.Principal},
+	} {
+		if field.value != "" {
+			attrs = append(attrs, field.key, field.value)
+		}
+	}
+	attrs = append(attrs,
+		"backend_session", event.BackendSession,
+		"canary", event.Canary,
+		"streaming", event.Streaming,
+		"body_bytes", event.BodyBytes)
+	logger().Info("request routed", attrs...)
+}
+
+// createRoutingResponse creates a response with routing headers and session mapping, bounding the
+// backend's response time by timeout when it is set
+func (s *Server) createRoutingResponse(toolName string, bodyBytes []byte, routeTarget, backendSession, backendSessionHeader string, timeout time.Duration, requestID any) []*eppb.ProcessingResponse {
+	s.debugf("[EXT-PROC] 🔧 createRoutingResponse - streaming: %v, route: %s, session: %s (%s)", s.streaming, routeTarget, backendSession, backendSessionHeader)
 
 	headers := []*basepb.HeaderValueOption{
 		{
@@ -226,16 +670,60 @@ func (s *Server) createRoutingResponse(toolName string, bodyBytes []byte, routeT
 		},
 	}
 
+	// Backend logs can show the name the client used as well as the stripped one they received
+	if s.config.OriginalToolNameHeader {
+		headers = append(headers, &basepb.HeaderValueOption{
+			Header: &basepb.HeaderValue{
+				Key:      originalToolHeader,
+				RawValue: []byte(toolName),
+			},
+		})
+	}
+
 	// Add backend session header if we have one
 	if backendSession != "" {
 		headers = append(headers, &basepb.HeaderValueOption{
 			Header: &basepb.HeaderValue{
-				Key:      sessionHeader,
+				Key:      backendSessionHeader,
 				RawValue: []byte(backendSession),
 			},
 		})
 	}
 
+	// Stateless backends and those using a different session header must not see the helper session ID
+	var removeHeaders []string
+	if backendSessionHeader != sessionHeader || backendSession == "" {
+		removeHeaders = append(removeHeaders, sessionHeader)
+	}
+
+	// Bound slow tool execution per backend; Envoy turns a timeout into a JSON-RPC -32001 error
+	if timeout > 0 {
+		headers = append(headers, &basepb.HeaderValueOption{
+			Header: &basepb.HeaderValue{
+				Key:      upstreamTimeoutHeader,
+				RawValue: []byte(fmt.Sprintf("%d", timeout.Milliseconds())),
+			},
+		})
+	}
+	if idBytes, err := json.Marshal(requestID); err == nil {
+		headers = append(headers, &basepb.HeaderValueOption{
+			Header: &basepb.HeaderValue{
+				Key:      jsonrpcIDHeader,
+				RawValue: idBytes,
+			},
+		})
+	}
+
+	// The rewritten body is always JSON, whatever content-type the client sent
+	if s.config.BackendContentType != "" {
+		headers = append(headers, &basepb.HeaderValueOption{
+			Header: &basepb.HeaderValue{
+				Key:      "content-type",
+				RawValue: []byte(s.config.BackendContentType),
+			},
+		})
+	}
+
 	// Update content-length header to match the modified body
 	contentLength := fmt.Sprintf("%d", len(bodyBytes))
 	headers = append(headers, &basepb.HeaderValueOption{
@@ -246,7 +734,7 @@ func (s *Server) createRoutingResponse(toolName string, bodyBytes []byte, routeT
 	})
 
 	if s.streaming {
-		log.Printf("[EXT-PROC] 🚀 Using streaming mode - returning header response first")
+		s.debugf("[EXT-PROC] 🚀 Using streaming mode - returning header response first")
 		ret := []*eppb.ProcessingResponse{
 			{
 				Response: &eppb.ProcessingResponse_RequestHeaders{
@@ -254,7 +742,8 @@ func (s *Server) createRoutingResponse(toolName string, bodyBytes []byte, routeT
 						Response: &eppb.CommonResponse{
 							ClearRouteCache: true,
 							HeaderMutation: &eppb.HeaderMutation{
-								SetHeaders: headers,
+								SetHeaders:    headers,
+								RemoveHeaders: removeHeaders,
 							},
 						},
 					},
@@ -262,13 +751,13 @@ func (s *Server) createRoutingResponse(toolName string, bodyBytes []byte, routeT
 			},
 		}
 		ret = addStreamedBodyResponse(ret, bodyBytes)
-		log.Printf("[EXT-PROC] Completed MCP processing with routing to %s (streaming)", routeTarget)
+		s.debugf("[EXT-PROC] Completed MCP processing with routing to %s (streaming)", routeTarget)
 		return ret
 	}
 
 	// For non-streaming: Set headers in RequestBody response with ClearRouteCache
-	log.Printf("[EXT-PROC] 📦 Using non-streaming mode - setting headers in body response")
-	log.Printf("[EXT-PROC] Completed MCP processing with routing to %s", routeTarget)
+	s.debugf("[EXT-PROC] 📦 Using non-streaming mode - setting headers in body response")
+	s.debugf("[EXT-PROC] Completed MCP processing with routing to %s", routeTarget)
 	return []*eppb.ProcessingResponse{
 		{
 			Response: &eppb.ProcessingResponse_RequestBody{
@@ -277,7 +766,8 @@ func (s *Server) createRoutingResponse(toolName string, bodyBytes []byte, routeT
 						// Necessary so that the new headers are used in the routing decision.
 						ClearRouteCache: true,
 						HeaderMutation: &eppb.HeaderMutation{
-							SetHeaders: headers,
+							SetHeaders:    headers,
+							RemoveHeaders: removeHeaders,
 						},
 						BodyMutation: &eppb.BodyMutation{
 							Mutation: &eppb.BodyMutation_Body{
@@ -331,6 +821,157 @@ func (s *Server) createEmptyBodyResponse() []*eppb.ProcessingResponse {
 	}
 }
 
+// findMissingRequiredArgument returns the first required argument absent from params.arguments,
+// or an empty string if all are present or the tool schema isn't known
+func (s *Server) findMissingRequiredArgument(toolName string, data map[string]any) string {
+	lookup, ok := s.helper.(ToolSchemaLookup)
+	if !ok {
+		return ""
+	}
+
+	required, found := lookup.GetToolRequiredArguments(toolName)
+	if !found || len(required) == 0 {
+		return ""
+	}
+
+	var arguments map[string]interface{}
+	if params, ok := data["params"].(map[string]interface{}); ok {
+		arguments, _ = params["arguments"].(map[string]interface{})
+	}
+
+	for _, name := range required {
+		if _, present := arguments[name]; !present {
+			return name
+		}
+	}
+	return ""
+}
+
+// cacheScope returns whose cached results a call may share: the principal's when authenticated,
+// otherwise only those of the same helper session. Backend sessions can't scope anonymous callers,
+// since every client of a stateless backend has the same empty one.
+func cacheScope(principal, helperSession string) string {
+	if principal != "" {
+		return "principal:" + principal
+	}
+	return "session:" + helperSession
+}
+
+// responseCacheKey returns the cache key for a tool call if caching is enabled and the tool is read-only
+func (s *Server) responseCacheKey(scope, toolName, routeTarget, strippedToolName string, data map[string]any) (string, bool) {
+	if s.cache == nil {
+		return "", false
+	}
+
+	lookup, ok := s.helper.(ToolSchemaLookup)
+	if !ok || !lookup.IsToolReadOnly(toolName) {
+		return "", false
+	}
+
+	var arguments any
+	if params, ok := data["params"].(map[string]interface{}); ok {
+		arguments = params["arguments"]
+	}
+	return cacheKey(scope, routeTarget, strippedToolName, arguments)
+}
+
+// createJSONRPCResultResponse creates an immediate JSON-RPC result response for the given request ID
+func (s *Server) createJSONRPCResultResponse(id any, result json.RawMessage) []*eppb.ProcessingResponse {
+	body, err := json.Marshal(map[string]any{
+		"jsonrpc": "2.0",
+		"id":      id,
+		"result":  result,
+	})
+	if err != nil {
+		log.Printf("[EXT-PROC] Failed to marshal JSON-RPC result: %v", err)
+		return s.createErrorResponse("Failed to build cached response", 500)
+	}
+	return s.createImmediateJSONResponse(body, "ext-proc cached result")
+}
+
+// createJSONRPCErrorResponse creates an immediate JSON-RPC error response for the given request ID
+func (s *Server) createJSONRPCErrorResponse(id any, code int, message string) []*eppb.ProcessingResponse {
+	log.Printf("[EXT-PROC] 🚫 Returning JSON-RPC error %d: %s", code, message)
+
+	body, err := json.Marshal(map[string]any{
+		"jsonrpc": "2.0",
+		"id":      id,
+		"error": map[string]any{
+			"code":    code,
+			"message": message,
+		},
+	})
+	if err != nil {
+		log.Printf("[EXT-PROC] Failed to marshal JSON-RPC error: %v", err)
+		return s.createErrorResponse(message, 400)
+	}
+	return s.createImmediateJSONResponse(body, fmt.Sprintf("ext-proc JSON-RPC error: %s", message))
+}
+
+// createImmediateJSONResponse creates an immediate 200 response carrying a JSON-RPC body
+func (s *Server) createImmediateJSONResponse(body []byte, details string) []*eppb.ProcessingResponse {
+	return []*eppb.ProcessingResponse{
+		{
+			Response: &eppb.ProcessingResponse_ImmediateResponse{
+				ImmediateResponse: &eppb.ImmediateResponse{
+					Status: &typepb.HttpStatus{
+						Code: typepb.StatusCode_OK,
+					},
+					Headers: &eppb.HeaderMutation{
+						SetHeaders: []*basepb.HeaderValueOption{
+							{
+								Header: &basepb.HeaderValue{
+									Key:      "content-type",
+									RawValue: []byte("application/json"),
+								},
+							},
+						},
+					},
+					Body:    body,
+					Details: details,
+				},
+			},
+		},
+	}
+}
+
+// responseTimeout returns the response timeout for a tool call: a per-tool entry in the config wins, then
+// the timeout the tool's annotations ask for, then the backend's timeout
+func (s *Server) responseTimeout(toolName, routeTarget string) (time.Duration, bool) {
+	if timeout, ok := s.config.BackendResponseTimeouts[toolName]; ok && timeout > 0 {
+		return timeout, true
+	}
+	if hints, ok := s.helper.(ToolTimeouts); ok {
+		if timeout, ok := hints.GetToolTimeout(toolName); ok && timeout > 0 {
+			return timeout, true
+		}
+	}
+	if timeout, ok := s.config.BackendResponseTimeouts[routeTarget]; ok && timeout > 0 {
+		return timeout, true
+	}
+	return 0, false
+}
+
+// ResponseTimeout returns the response timeout a call to toolName on target gets, for diagnostics.
+// It is safe to call on a nil Server, which applies none.
+func (s *Server) ResponseTimeout(toolName, target string) (time.Duration, bool) {
+	if s == nil {
+		return 0, false
+	}
+	return s.responseTimeout(toolName, target)
+}
+
+// routeFailure applies the configured route failure mode to a tool call that can't be routed
+func (s *Server) routeFailure(ctx context.Context, data map[string]any, message string, statusCode int32) []*eppb.ProcessingResponse {
+	s.deadLetter(ctx, data, message, statusCode)
+
+	if s.config.RouteFailureMode == RouteFailureOpen {
+		log.Printf("[EXT-PROC] ⚠️ %s, failing open to helper", message)
+		return s.createEmptyBodyResponse()
+	}
+	return s.createErrorResponse(message, statusCode)
+}
+
 // createErrorResponse creates an immediate error response with the specified status code
 func (s *Server) createErrorResponse(message string, statusCode int32) []*eppb.ProcessingResponse {
 	log.Printf("[EXT-PROC] 🚫 Returning %d error: %s", statusCode, message)
@@ -355,8 +996,8 @@ func (s *Server) HandleRequestHeaders(headers *eppb.HttpHeaders) ([]*eppb.Proces
 	log.Printf("[EXT-PROC] 🔍 HandleRequestHeaders called - streaming: %v", s.streaming)
 	if headers != nil && headers.Headers != nil {
 		for _, header := range headers.Headers.Headers {
-			if strings.ToLower(header.Key) == "content-type" || strings.ToLower(header.Key) == "mcp-session-id" {
-				log.Printf("[EXT-PROC] 🔍 Header: %s = %s", header.Key, string(header.RawValue))
+			if strings.ToLower(header.Key) == "content-type" || strings.ToLower(header.Key) == sessionHeader {
+				s.debugf("[EXT-PROC] 🔍 Header: %s = %s", header.Key, string(header.RawValue))
 			}
 		}
 	}
@@ -0,0 +1,448 @@
+package handlers
+
+import (
+	"encoding/json"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+// routedToolCall is a tools/call for server1-echo on session helper-1
+const routedToolCall = `{"jsonrpc":"2.0","id":1,"method":"tools/call","params":{"name":"server1-echo","arguments":{"text":"hi"}}}`
+
+// routingTestServer returns a server routing server1- tools on a session mapped to backend session backend-1
+func routingTestServer(t *testing.T, streaming bool) *Server {
+	t.Helper()
+
+	useBackends(t, serverConfig{prefix: "server1-", target: "server1"})
+	return NewServer(streaming, newFakeHelper("helper-1", map[string]string{"server1": "backend-1"}), Config{
+		OriginalToolNameHeader:  true,
+		BackendContentType:      "application/json",
+		BackendResponseTimeouts: map[string]time.Duration{"server1": 30 * time.Second},
+	})
+}
+
+// assertRoutingHeaders checks every header the routing response sets for a server1-echo call
+func assertRoutingHeaders(t *testing.T, headers map[string]string, body []byte) {
+	t.Helper()
+
+	want := map[string]string{
+		toolHeader:            "server1-echo",
+		serverHeader:          "server1",
+		originalToolHeader:    "server1-echo",
+		defaultSessionHeader:  "backend-1",
+		upstreamTimeoutHeader: "30000",
+		jsonrpcIDHeader:       "1",
+		"content-type":        "application/json",
+		"content-length":      strconv.Itoa(len(body)),
+	}
+	for name, value := range want {
+		if headers[name] != value {
+			t.Errorf("header %s = %q, want %q", name, headers[name], value)
+		}
+	}
+	if len(headers) != len(want) {
+		t.Errorf("got headers %v, want exactly %v", headers, want)
+	}
+}
+
+// assertStrippedBody checks the body sent to the backend calls the tool by its backend name
+func assertStrippedBody(t *testing.T, body []byte) {
+	t.Helper()
+
+	var request struct {
+		Params struct {
+			Name      string         `json:"name"`
+			Arguments map[string]any `json:"arguments"`
+		} `json:"params"`
+	}
+	if err := json.Unmarshal(body, &request); err != nil {
+		t.Fatalf("routed body %q is not JSON: %v", body, err)
+	}
+	if request.Params.Name != "echo" {
+		t.Errorf("routed tool name = %q, want echo", request.Params.Name)
+	}
+	if request.Params.Arguments["text"] != "hi" {
+		t.Errorf("routed arguments = %v, want the client's", request.Params.Arguments)
+	}
+}
+
+func TestHandleRequestBodyRoutesToolCall(t *testing.T) {
+	s := routingTestServer(t, false)
+
+	route := &routeState{}
+	responses, err := s.HandleRequestBody(requestContext("helper-1"), decodeTestBody(t, routedToolCall), route)
+	if err != nil {
+		t.Fatalf("HandleRequestBody() error = %v", err)
+	}
+	if len(responses) != 1 {
+		t.Fatalf("got %d responses, want 1", len(responses))
+	}
+
+	common := responses[0].GetRequestBody().GetResponse()
+	if common == nil {
+		t.Fatalf("response is not a request body response: %v", responses[0])
+	}
+	if !common.GetClearRouteCache() {
+		t.Error("ClearRouteCache is not set, Envoy would route on the old headers")
+	}
+	body := common.GetBodyMutation().GetBody()
+	assertStrippedBody(t, body)
+	assertRoutingHeaders(t, setHeaders(common.GetHeaderMutation()), body)
+	if removed := common.GetHeaderMutation().GetRemoveHeaders(); len(removed) != 0 {
+		t.Errorf("removed headers %v, want none when the backend shares the helper session header", removed)
+	}
+
+	if route.target != "server1" || route.name != "server1-echo" || route.helperSession != "helper-1" {
+		t.Errorf("route = %+v, want server1-echo routed to server1 for helper-1", route)
+	}
+}
+
+func TestHandleRequestBodyStreamingOrder(t *testing.T) {
+	s := routingTestServer(t, true)
+
+	responses, err := s.HandleRequestBody(requestContext("helper-1"), decodeTestBody(t, routedToolCall), &routeState{})
+	if err != nil {
+		t.Fatalf("HandleRequestBody() error = %v", err)
+	}
+	if len(responses) != 2 {
+		t.Fatalf("got %d responses, want headers then body", len(responses))
+	}
+
+	// Headers go first so Envoy re-routes before any body bytes reach the old route
+	headers := responses[0].GetRequestHeaders().GetResponse()
+	if headers == nil {
+		t.Fatalf("first response is not a request headers response: %v", responses[0])
+	}
+	if !headers.GetClearRouteCache() {
+		t.Error("ClearRouteCache is not set on the headers response")
+	}
+
+	streamed := responses[1].GetRequestBody().GetResponse().GetBodyMutation().GetStreamedResponse()
+	if streamed == nil {
+		t.Fatalf("second response is not a streamed body mutation: %v", responses[1])
+	}
+	if !streamed.GetEndOfStream() {
+		t.Error("streamed body does not end the stream")
+	}
+	assertStrippedBody(t, streamed.GetBody())
+	assertRoutingHeaders(t, setHeaders(headers.GetHeaderMutation()), streamed.GetBody())
+}
+
+func TestJSONRPCIDsSurviveRouting(t *testing.T) {
+	tests := []struct {
+		name string
+		id   string
+	}{
+		{name: "string", id: `"req-1"`},
+		{name: "integer", id: `42`},
+		{name: "integer above 2^53", id: `9007199254740993`},
+		{name: "null", id: `null`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := routingTestServer(t, false)
+			body := decodeTestBody(t, `{"jsonrpc":"2.0","id":`+tt.id+`,"method":"tools/call","params":{"name":"server1-echo","arguments":{"text":"hi"}}}`)
+
+			responses, err := s.HandleRequestBody(requestContext("helper-1"), body, &routeState{})
+			if err != nil {
+				t.Fatalf("HandleRequestBody() error = %v", err)
+			}
+			headers := setHeaders(responses[0].GetRequestBody().GetResponse().GetHeaderMutation())
+			if headers[jsonrpcIDHeader] != tt.id {
+				t.Errorf("header %s = %q, want %q", jsonrpcIDHeader, headers[jsonrpcIDHeader], tt.id)
+			}
+
+			errorResponse := s.createJSONRPCErrorResponse(body["id"], invalidParamsCode, "bad params")
+			var reply struct {
+				ID json.RawMessage `json:"id"`
+			}
+			if err := json.Unmarshal(errorResponse[0].GetImmediateResponse().GetBody(), &reply); err != nil {
+				t.Fatalf("error response is not JSON: %v", err)
+			}
+			if string(reply.ID) != tt.id {
+				t.Errorf("error response id = %s, want %s", reply.ID, tt.id)
+			}
+		})
+	}
+}
+
+// usePrefixTestBackends registers backends with a plain prefix, a prefix extending it and a tool group
+func usePrefixTestBackends(t *testing.T) {
+	t.Helper()
+
+	useBackends(t,
+		serverConfig{prefix: "server1-", target: "server1"},
+		serverConfig{prefix: "server1-x-", target: "server2"},
+		serverConfig{prefix: "server3-", target: "server3"},
+	)
+	if err := SetToolGroup("server3", "grp", "/"); err != nil {
+		t.Fatalf("SetToolGroup() error = %v", err)
+	}
+}
+
+func TestToolPrefixRoundTrip(t *testing.T) {
+	usePrefixTestBackends(t)
+
+	tests := []struct {
+		name       string
+		target     string
+		tool       string
+		aggregated string
+	}{
+		{name: "plain", target: "server1", tool: "echo", aggregated: "server1-echo"},
+		{name: "tool name containing the separator", target: "server1", tool: "get-user-info", aggregated: "server1-get-user-info"},
+		{name: "empty tool name", target: "server1", tool: "", aggregated: "server1-"},
+		{name: "prefix extending another prefix", target: "server2", tool: "echo", aggregated: "server1-x-echo"},
+		{name: "group namespace", target: "server3", tool: "echo", aggregated: "grp/echo"},
+		{name: "group tool name containing the group separator", target: "server3", tool: "a/b", aggregated: "grp/a/b"},
+		{name: "group tool name containing the prefix separator", target: "server3", tool: "server1-echo", aggregated: "grp/server1-echo"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			aggregated := ToolPrefix(tt.target) + tt.tool
+			if aggregated != tt.aggregated {
+				t.Fatalf("aggregated name = %q, want %q", aggregated, tt.aggregated)
+			}
+			target, tool, ok := StripToolPrefix(aggregated)
+			if !ok || target != tt.target || tool != tt.tool {
+				t.Errorf("StripToolPrefix(%q) = (%q, %q, %t), want (%q, %q, true)", aggregated, target, tool, ok, tt.target, tt.tool)
+			}
+		})
+	}
+}
+
+func TestStripToolPrefix(t *testing.T) {
+	usePrefixTestBackends(t)
+
+	tests := []struct {
+		name     string
+		toolName string
+		target   string
+		tool     string
+		ok       bool
+	}{
+		{name: "flat alias of a grouped tool", toolName: "server3-echo", target: "server3", tool: "echo", ok: true},
+		{name: "longest prefix wins", toolName: "server1-x-echo", target: "server2", tool: "echo", ok: true},
+		{name: "unknown prefix", toolName: "other-echo", tool: "other-echo"},
+		{name: "empty name", toolName: "", tool: ""},
+		{name: "prefix without separator", toolName: "server1", tool: "server1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			target, tool, ok := StripToolPrefix(tt.toolName)
+			if target != tt.target || tool != tt.tool || ok != tt.ok {
+				t.Errorf("StripToolPrefix(%q) = (%q, %q, %t), want (%q, %q, %t)", tt.toolName, target, tool, ok, tt.target, tt.tool, tt.ok)
+			}
+		})
+	}
+}
+
+// restrictedHelper is a fakeHelper that hides tools from every principal but their allowlisted ones
+type restrictedHelper struct {
+	*fakeHelper
+	allowed map[string]string // aggregated tool name -> the one principal allowed to see it
+}
+
+func (r *restrictedHelper) IsToolVisible(toolName, principal string) bool {
+	allowed, restricted := r.allowed[toolName]
+	return !restricted || allowed == principal
+}
+
+func TestHiddenGroupedToolIsNotReachableByAlias(t *testing.T) {
+	useBackends(t, serverConfig{prefix: "server1-", target: "server1"})
+	if err := SetToolGroup("server1", "grp", "/"); err != nil {
+		t.Fatalf("SetToolGroup() error = %v", err)
+	}
+
+	tests := []struct {
+		name      string
+		toolName  string
+		principal string
+		visible   bool
+	}{
+		{name: "grouped name", toolName: "grp/secret", principal: "bob"},
+		{name: "flat alias", toolName: "server1-secret", principal: "bob"},
+		{name: "grouped name, allowed principal", toolName: "grp/secret", principal: "alice", visible: true},
+		{name: "flat alias, allowed principal", toolName: "server1-secret", principal: "alice", visible: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			helper := newFakeHelper("helper-1", map[string]string{"server1": "backend-1"})
+			helper.sessions["helper-1"].Principal = tt.principal
+			s := NewServer(false, &restrictedHelper{fakeHelper: helper, allowed: map[string]string{"grp/secret": "alice"}}, Config{})
+
+			body := decodeTestBody(t, `{"jsonrpc":"2.0","id":1,"method":"tools/call","params":{"name":"`+tt.toolName+`","arguments":{}}}`)
+			responses, err := s.HandleRequestBody(requestContext("helper-1"), body, &routeState{})
+			if err != nil {
+				t.Fatalf("HandleRequestBody() error = %v", err)
+			}
+			rejected := responses[0].GetImmediateResponse() != nil
+			if rejected == tt.visible {
+				t.Errorf("%s called by %s: rejected = %t, want %t", tt.toolName, tt.principal, rejected, !tt.visible)
+			}
+		})
+	}
+}
+
+func TestUnknownSessionIsNotFound(t *testing.T) {
+	s := routingTestServer(t, false)
+
+	responses, err := s.HandleRequestBody(requestContext("evicted-session"), decodeTestBody(t, routedToolCall), &routeState{})
+	if err != nil {
+		t.Fatalf("HandleRequestBody() error = %v", err)
+	}
+	immediate := responses[0].GetImmediateResponse()
+	if immediate == nil {
+		t.Fatalf("got %v, want an immediate response", responses[0])
+	}
+	if code := immediate.GetStatus().GetCode(); code != 404 {
+		t.Errorf("status = %d, want 404 so the client re-initializes", code)
+	}
+}
+
+// timeoutHelper is a fakeHelper whose tools ask for response timeouts in their annotations
+type timeoutHelper struct {
+	*fakeHelper
+	timeouts map[string]time.Duration
+}
+
+func (h *timeoutHelper) GetToolTimeout(toolName string) (time.Duration, bool) {
+	timeout, ok := h.timeouts[toolName]
+	return timeout, ok
+}
+
+func TestResponseTimeoutPrecedence(t *testing.T) {
+	helper := &timeoutHelper{
+		fakeHelper: newFakeHelper("helper-1", map[string]string{"server1": "backend-1"}),
+		timeouts:   map[string]time.Duration{"server1-long_job": 5 * time.Minute, "server1-pinned": time.Minute},
+	}
+	s := NewServer(false, helper, Config{
+		BackendResponseTimeouts: map[string]time.Duration{"server1": 30 * time.Second, "server1-pinned": 2 * time.Minute},
+	})
+
+	tests := []struct {
+		tool string
+		want time.Duration
+	}{
+		{"server1-echo", 30 * time.Second},    // backend timeout
+		{"server1-long_job", 5 * time.Minute}, // annotation hint over the backend's
+		{"server1-pinned", 2 * time.Minute},   // configured tool entry over the hint
+	}
+	for _, tt := range tests {
+		if got, ok := s.ResponseTimeout(tt.tool, "server1"); !ok || got != tt.want {
+			t.Errorf("ResponseTimeout(%s) = %s, %v, want %s", tt.tool, got, ok, tt.want)
+		}
+	}
+
+	var unset *Server
+	if _, ok := unset.ResponseTimeout("server1-long_job", "server1"); ok {
+		t.Error("nil server reported a timeout")
+	}
+}
+
+// restrictedSchemaHelper is a schemaHelper that also hides tools from every principal but their allowlisted ones
+type restrictedSchemaHelper struct {
+	*schemaHelper
+	allowed map[string]string
+}
+
+func (r *restrictedSchemaHelper) IsToolVisible(toolName, principal string) bool {
+	allowed, restricted := r.allowed[toolName]
+	return !restricted || allowed == principal
+}
+
+func TestHiddenToolRevealsNothing(t *testing.T) {
+	useBackends(t, serverConfig{prefix: "server1-", target: "server1"})
+	helper := newSchemaHelper()
+	helper.sessions["helper-1"].Principal = "bob"
+	s := NewServer(false, &restrictedSchemaHelper{schemaHelper: helper, allowed: map[string]string{"server1-echo": "alice"}},
+		Config{ValidateRequiredArgs: true})
+
+	// Missing the required argument must not tell bob the hidden tool exists
+	body := decodeTestBody(t, `{"jsonrpc":"2.0","id":1,"method":"tools/call","params":{"name":"server1-echo","arguments":{}}}`)
+	responses, err := s.HandleRequestBody(requestContext("helper-1"), body, &routeState{})
+	if err != nil {
+		t.Fatalf("HandleRequestBody() error = %v", err)
+	}
+	immediate := responses[0].GetImmediateResponse()
+	if immediate == nil {
+		t.Fatalf("got %v, want the call rejected", responses[0])
+	}
+	if got := string(immediate.GetBody()); !strings.Contains(got, "Unknown tool: server1-echo") {
+		t.Errorf("body = %s, want the unknown tool error", got)
+	}
+}
+
+func TestToolCallsCountedByProcessingMode(t *testing.T) {
+	useBackends(t, serverConfig{prefix: "server1-", target: "server1"})
+	for _, tt := range []struct {
+		streaming bool
+		mode      string
+	}{
+		{streaming: true, mode: "streaming"},
+		{streaming: false, mode: "buffered"},
+	} {
+		t.Run(tt.mode, func(t *testing.T) {
+			counter := toolCallsTotal.WithLabelValues("server1", "server1-echo", tt.mode, "false")
+			before := testutil.ToFloat64(counter)
+
+			s := NewServer(tt.streaming, newSchemaHelper(), Config{})
+			if _, err := s.HandleRequestBody(requestContext("helper-1"), decodeTestBody(t, routedToolCall), &routeState{}); err != nil {
+				t.Fatalf("HandleRequestBody() error = %v", err)
+			}
+			if got := testutil.ToFloat64(counter) - before; got != 1 {
+				t.Errorf("mode=%s counter grew by %v, want 1", tt.mode, got)
+			}
+		})
+	}
+}
+
+func TestCanaryCallUsesTheStableTimeout(t *testing.T) {
+	useBackends(t, serverConfig{prefix: "server1-", target: "server1"})
+	s := NewServer(false, newFakeHelper("helper-1", map[string]string{"server1": "backend-1"}), Config{
+		BackendResponseTimeouts: map[string]time.Duration{"server1": 30 * time.Second, "server1-canary": 5 * time.Second},
+		Canary:                  CanaryConfig{Rules: []CanaryRule{{Match: "server1", CanaryTarget: "server1-canary", Percent: 100}}},
+	})
+	counter := toolCallsTotal.WithLabelValues("server1-canary", "server1-echo", "buffered", "true")
+	before := testutil.ToFloat64(counter)
+
+	responses, err := s.HandleRequestBody(requestContext("helper-1"), decodeTestBody(t, routedToolCall), &routeState{})
+	if err != nil {
+		t.Fatalf("HandleRequestBody() error = %v", err)
+	}
+	headers := setHeaders(responses[0].GetRequestBody().GetResponse().GetHeaderMutation())
+	if headers[serverHeader] != "server1-canary" {
+		t.Fatalf("routed to %q, want the canary", headers[serverHeader])
+	}
+	if headers[upstreamTimeoutHeader] != "30000" {
+		t.Errorf("canary timeout = %q, want the stable backend's 30000", headers[upstreamTimeoutHeader])
+	}
+	if got := testutil.ToFloat64(counter) - before; got != 1 {
+		t.Errorf("canary counter grew by %v, want 1", got)
+	}
+}
+
+func TestAliasUsesTheAggregatedToolsSchema(t *testing.T) {
+	useBackends(t, serverConfig{prefix: "server1-", target: "server1"})
+	if err := SetToolGroup("server1", "grp", "/"); err != nil {
+		t.Fatalf("SetToolGroup() error = %v", err)
+	}
+	helper := newSchemaHelper()
+	helper.required = map[string][]string{"grp/echo": {"text"}}
+	s := NewServer(false, helper, Config{ValidateRequiredArgs: true})
+
+	// The flat alias is checked against the schema of the grouped tool it resolves to
+	body := decodeTestBody(t, `{"jsonrpc":"2.0","id":1,"method":"tools/call","params":{"name":"server1-echo","arguments":{}}}`)
+	responses, err := s.HandleRequestBody(requestContext("helper-1"), body, &routeState{})
+	if err != nil {
+		t.Fatalf("HandleRequestBody() error = %v", err)
+	}
+	immediate := responses[0].GetImmediateResponse()
+	if immediate == nil || !strings.Contains(string(immediate.GetBody()), "Missing required argument 'text'") {
+		t.Fatalf("got %v, want the missing argument error", responses[0])
+	}
+}
@@ -0,0 +1,70 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+
+	eppb "github.com/envoyproxy/go-control-plane/envoy/service/ext_proc/v3"
+)
+
+// ResourcePrefix returns the prefix the helper adds to resource URIs from a backend target.
+// Resources always use the flat prefix, since a tool group and its separator wouldn't leave a usable URI.
+func ResourcePrefix(target string) string {
+	for _, config := range serverConfigs {
+		if config.target == target {
+			return config.prefix
+		}
+	}
+	return target + "-"
+}
+
+// StripResourcePrefix returns the backend target and backend URI for an aggregated resource URI.
+// The longest matching prefix wins, as it does for tools.
+func StripResourcePrefix(uri string) (target, backendURI string, ok bool) {
+	var match serverConfig
+	for _, config := range serverConfigs {
+		if config.prefix != "" && strings.HasPrefix(uri, config.prefix) && len(config.prefix) > len(match.prefix) {
+			match = config
+		}
+	}
+	if match.target == "" {
+		return "", uri, false
+	}
+	return match.target, strings.TrimPrefix(uri, match.prefix), true
+}
+
+// extractMCPResourceURI safely extracts the resource URI from an MCP resources/read request
+func extractMCPResourceURI(data map[string]any) string {
+	if extractMCPMethod(data) != "resources/read" {
+		return ""
+	}
+
+	params, ok := data["params"].(map[string]any)
+	if !ok {
+		log.Println("[EXT-PROC] MCP resource read params is not an object")
+		return ""
+	}
+	uri, ok := params["uri"].(string)
+	if !ok {
+		log.Println("[EXT-PROC] MCP resource read missing uri in params")
+		return ""
+	}
+	return uri
+}
+
+// handleResourceRead routes a resources/read request to the backend owning the URI, rewriting it
+// to the backend's own URI the way stripServerPrefix does for tool names
+func (s *Server) handleResourceRead(ctx context.Context, data map[string]any, uri string, route *routeState) []*eppb.ProcessingResponse {
+	s.debugf("[EXT-PROC] Resource URI: %s", uri)
+
+	routeTarget, backendURI, ok := StripResourcePrefix(uri)
+	if !ok {
+		log.Printf("[EXT-PROC] Resource URI '%s' doesn't match any server prefix", uri)
+		return s.routeFailure(ctx, data, fmt.Sprintf("Unknown resource: %s", uri), 404)
+	}
+
+	return s.routeRenamedRequest(ctx, data, route, "uri", uri, routeTarget, backendURI,
+		routingEvent{Resource: uri, StrippedResource: backendURI})
+}
@@ -1,60 +1,112 @@
 package handlers
 
 import (
+	"bytes"
+	"encoding/json"
 	"log"
+	"strconv"
 	"strings"
+	"time"
 
 	basepb "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
 	eppb "github.com/envoyproxy/go-control-plane/envoy/service/ext_proc/v3"
 )
 
-// Session prefixes for reverse mapping
-var sessionPrefixes = []string{"server1-session-", "server2-session-"}
-
 // extractHelperSessionFromBackend extracts the helper session ID from a backend session ID
+// of the form "<target>-session-<helper session>"
 // Returns empty string if not a backend session ID
 func extractHelperSessionFromBackend(backendSessionID string) string {
-	for _, prefix := range sessionPrefixes {
-		if strings.HasPrefix(backendSessionID, prefix) {
-			return strings.TrimPrefix(backendSessionID, prefix)
+	for _, config := range serverConfigs {
+		if helperSession, found := strings.CutPrefix(backendSessionID, config.target+"-session-"); found {
+			return helperSession
 		}
 	}
 	return ""
 }
 
-// HandleResponseHeaders handles response headers for session ID reverse mapping
-func (s *Server) HandleResponseHeaders(headers *eppb.HttpHeaders) ([]*eppb.ProcessingResponse, error) {
+// jsonRPCPayload returns the JSON-RPC message in a response body, taken from the first data line
+// when the backend answered with an SSE stream
+func jsonRPCPayload(body []byte) []byte {
+	payload := bytes.TrimSpace(body)
+	if bytes.HasPrefix(payload, []byte("event:")) || bytes.HasPrefix(payload, []byte("data:")) {
+		for _, line := range bytes.Split(payload, []byte("\n")) {
+			if data, found := bytes.CutPrefix(bytes.TrimSpace(line), []byte("data:")); found {
+				return bytes.TrimSpace(data)
+			}
+		}
+	}
+	return payload
+}
+
+// jsonRPCError reports whether a response body, plain JSON or a single SSE message, is a JSON-RPC
+// error response, returning its code and message for logging
+func jsonRPCError(body []byte) (string, string, bool) {
+	var response struct {
+		Error *struct {
+			Code    json.Number `json:"code"`
+			Message string      `json:"message"`
+		} `json:"error"`
+	}
+	if err := json.Unmarshal(jsonRPCPayload(body), &response); err != nil || response.Error == nil {
+		return "", "", false
+	}
+	return response.Error.Code.String(), response.Error.Message, true
+}
+
+// isCacheableResponse reports whether response headers describe a 200 JSON response
+func isCacheableResponse(headers *eppb.HttpHeaders) bool {
+	if headers == nil || headers.Headers == nil {
+		return false
+	}
+
+	var statusOK, isJSON bool
+	for _, header := range headers.Headers.Headers {
+		switch strings.ToLower(header.Key) {
+		case ":status":
+			statusOK = string(header.RawValue) == "200"
+		case "content-type":
+			isJSON = strings.HasPrefix(string(header.RawValue), "application/json")
+		}
+	}
+	return statusOK && isJSON
+}
+
+// HandleResponseHeaders handles response headers for session ID reverse mapping and status remapping.
+// The route recorded during the request phase selects which header carries the backend session.
+func (s *Server) HandleResponseHeaders(headers *eppb.HttpHeaders, route *routeState) ([]*eppb.ProcessingResponse, error) {
 	log.Println("[EXT-PROC] Processing response headers for session mapping...")
 
+	responseSessionHeader := sessionHeader
+	if route != nil && route.sessionHeader != "" {
+		responseSessionHeader = route.sessionHeader
+	}
+
+	// Only plain successful JSON responses are cached, never SSE streams or errors
+	if route != nil && route.cacheKey != "" && !isCacheableResponse(headers) {
+		log.Println("[EXT-PROC] Response not cacheable, skipping cache")
+		route.cacheKey = ""
+	}
+
 	if headers == nil || headers.Headers == nil {
 		log.Println("[EXT-PROC] No response headers to process")
-		return []*eppb.ProcessingResponse{
-			{
-				Response: &eppb.ProcessingResponse_ResponseHeaders{
-					ResponseHeaders: &eppb.HeadersResponse{},
-				},
-			},
-		}, nil
+		return responseHeadersMutation(nil, nil), nil
 	}
 
-	// Look for mcp-session-id header that needs reverse mapping
+	// Backend error statuses are normalized into the gateway's own error contract
+	setHeaders := s.remapResponseStatus(headers, route)
+
+	// Look for the backend session header that needs reverse mapping
 	var mcpSessionID string
 	for _, header := range headers.Headers.Headers {
-		if strings.ToLower(header.Key) == "mcp-session-id" {
+		if strings.ToLower(header.Key) == responseSessionHeader {
 			mcpSessionID = string(header.RawValue)
 			break
 		}
 	}
 
 	if mcpSessionID == "" {
-		log.Println("[EXT-PROC] No mcp-session-id in response headers")
-		return []*eppb.ProcessingResponse{
-			{
-				Response: &eppb.ProcessingResponse_ResponseHeaders{
-					ResponseHeaders: &eppb.HeadersResponse{},
-				},
-			},
-		}, nil
+		log.Printf("[EXT-PROC] No %s in response headers", responseSessionHeader)
+		return responseHeadersMutation(setHeaders, nil), nil
 	}
 
 	log.Printf("[EXT-PROC] Response backend session: %s", mcpSessionID)
@@ -64,54 +116,122 @@ func (s *Server) HandleResponseHeaders(headers *eppb.HttpHeaders) ([]*eppb.Proce
 	if helperSession == "" {
 		// Not a backend session ID, leave as-is
 		log.Println("[EXT-PROC] Session ID doesn't need reverse mapping")
+		return responseHeadersMutation(setHeaders, nil), nil
+	}
+
+	log.Printf("[EXT-PROC] Mapping backend session back to helper session: %s", helperSession)
+
+	// Clients always see the helper session on the client session header
+	var removeHeaders []string
+	if responseSessionHeader != sessionHeader {
+		removeHeaders = append(removeHeaders, responseSessionHeader)
+	}
+
+	setHeaders = append(setHeaders, &basepb.HeaderValueOption{
+		Header: &basepb.HeaderValue{
+			Key:      sessionHeader,
+			RawValue: []byte(helperSession),
+		},
+	})
+	return responseHeadersMutation(setHeaders, removeHeaders), nil
+}
+
+// responseHeadersMutation builds the response headers response, leaving the headers untouched
+// when there is nothing to set or remove
+func responseHeadersMutation(setHeaders []*basepb.HeaderValueOption, removeHeaders []string) []*eppb.ProcessingResponse {
+	if len(setHeaders) == 0 && len(removeHeaders) == 0 {
 		return []*eppb.ProcessingResponse{
 			{
 				Response: &eppb.ProcessingResponse_ResponseHeaders{
 					ResponseHeaders: &eppb.HeadersResponse{},
 				},
 			},
-		}, nil
+		}
 	}
 
-	log.Printf("[EXT-PROC] Mapping backend session back to helper session: %s", helperSession)
-
-	// Return response with updated session header
 	return []*eppb.ProcessingResponse{
 		{
 			Response: &eppb.ProcessingResponse_ResponseHeaders{
 				ResponseHeaders: &eppb.HeadersResponse{
 					Response: &eppb.CommonResponse{
 						HeaderMutation: &eppb.HeaderMutation{
-							SetHeaders: []*basepb.HeaderValueOption{
-								{
-									Header: &basepb.HeaderValue{
-										Key:      "mcp-session-id",
-										RawValue: []byte(helperSession),
-									},
-								},
-							},
+							SetHeaders:    setHeaders,
+							RemoveHeaders: removeHeaders,
 						},
 					},
 				},
 			},
 		},
-	}, nil
+	}
 }
 
-// HandleResponseBody handles response bodies.
-func (s *Server) HandleResponseBody(body *eppb.HttpBody) ([]*eppb.ProcessingResponse, error) {
+// HandleResponseBody handles response bodies, populating the response cache for read-only tool calls.
+func (s *Server) HandleResponseBody(body *eppb.HttpBody, route *routeState) ([]*eppb.ProcessingResponse, error) {
 	log.Printf("[EXT-PROC] Processing response body... (size: %d, end_of_stream: %t)",
 		len(body.GetBody()), body.GetEndOfStream())
 
-	// Log the response body content if it's not too large
+	// JSON-RPC errors from the backend reach the client byte for byte; only headers are ever rewritten
+	if body.GetEndOfStream() {
+		if code, message, ok := jsonRPCError(body.GetBody()); ok {
+			attrs := []any{"code", code, "message", message}
+			if route != nil && route.target != "" {
+				attrs = append(attrs, "backend", route.target, "tool", route.name, "session_id", route.helperSession)
+			}
+			logger().Warn("backend error, passing through unchanged", attrs...)
+			if route != nil {
+				route.cacheKey = ""
+			}
+		}
+		if route != nil && !route.routedAt.IsZero() {
+			logger().Info("backend responded",
+				"backend", route.target,
+				"tool", route.name,
+				"session_id", route.helperSession,
+				"latency_ms", time.Since(route.routedAt).Milliseconds())
+		}
+	}
+
+	// Results that break the tool's declared output schema are caught before the client sees them
+	if body.GetEndOfStream() {
+		if response := s.checkToolResult(route, body.GetBody()); response != nil {
+			return response, nil
+		}
+	}
+
+	if s.cache != nil && route != nil && route.cacheKey != "" && body.GetEndOfStream() {
+		s.cache.storeFromResponseBody(route.cacheKey, body.GetBody())
+	}
+
+	// Every response body is only logged at debug level, or per backend when it has body logging on
 	if len(body.GetBody()) > 0 && len(body.GetBody()) < 1000 {
-		log.Printf("[EXT-PROC] Response body content: %s", string(body.GetBody()))
+		s.debugf("[EXT-PROC] Response body content: %s", s.redact.body(body.GetBody()))
+	}
+	s.logResponseBody(route, body.GetBody())
+
+	bodyResponse := &eppb.BodyResponse{}
+
+	// With a buffered response body, headers are still held by Envoy at end of stream and can be set here
+	if s.config.BackendDurationHeader && route != nil && !route.routedAt.IsZero() && body.GetEndOfStream() {
+		duration := time.Since(route.routedAt)
+		bodyResponse.Response = &eppb.CommonResponse{
+			HeaderMutation: &eppb.HeaderMutation{
+				SetHeaders: []*basepb.HeaderValueOption{
+					{
+						Header: &basepb.HeaderValue{
+							Key:      backendDurationHeader,
+							RawValue: []byte(strconv.FormatInt(duration.Milliseconds(), 10)),
+						},
+					},
+				},
+			},
+		}
+		s.debugf("[EXT-PROC] Backend %s took %s", route.target, duration)
 	}
 
 	return []*eppb.ProcessingResponse{
 		{
 			Response: &eppb.ProcessingResponse_ResponseBody{
-				ResponseBody: &eppb.BodyResponse{},
+				ResponseBody: bodyResponse,
 			},
 		},
 	}, nil
@@ -0,0 +1,74 @@
+package handlers
+
+import (
+	"testing"
+	"time"
+
+	basepb "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
+	eppb "github.com/envoyproxy/go-control-plane/envoy/service/ext_proc/v3"
+)
+
+func TestBackendJSONRPCErrorsPassThrough(t *testing.T) {
+	tests := []struct {
+		name        string
+		contentType string
+		body        string
+	}{
+		{
+			name:        "plain JSON",
+			contentType: "application/json",
+			body:        `{"jsonrpc":"2.0","id":1,"error":{"code":-32602,"message":"unknown tool"}}`,
+		},
+		{
+			name:        "SSE",
+			contentType: "text/event-stream",
+			body:        "event: message\ndata: {\"jsonrpc\":\"2.0\",\"id\":1,\"error\":{\"code\":-32602,\"message\":\"unknown tool\"}}\n\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			useBackends(t, serverConfig{prefix: "server1-", target: "server1"})
+			s := NewServer(false, newFakeHelper("helper-1", map[string]string{"server1": "server1-session-helper-1"}),
+				Config{ResponseCacheTTL: time.Minute})
+			route := &routeState{
+				target:        "server1",
+				sessionHeader: defaultSessionHeader,
+				cacheKey:      "server1|echo|{}",
+				name:          "server1-echo",
+				toolCall:      true,
+				helperSession: "helper-1",
+			}
+
+			headerResponses, err := s.HandleResponseHeaders(&eppb.HttpHeaders{Headers: &basepb.HeaderMap{Headers: []*basepb.HeaderValue{
+				{Key: ":status", RawValue: []byte("200")},
+				{Key: "content-type", RawValue: []byte(tt.contentType)},
+				{Key: defaultSessionHeader, RawValue: []byte("server1-session-helper-1")},
+			}}}, route)
+			if err != nil {
+				t.Fatalf("HandleResponseHeaders() error = %v", err)
+			}
+			headers := setHeaders(headerResponses[0].GetResponseHeaders().GetResponse().GetHeaderMutation())
+			if headers[sessionHeader] != "helper-1" {
+				t.Errorf("session header = %q, want the helper session helper-1", headers[sessionHeader])
+			}
+
+			bodyResponses, err := s.HandleResponseBody(&eppb.HttpBody{Body: []byte(tt.body), EndOfStream: true}, route)
+			if err != nil {
+				t.Fatalf("HandleResponseBody() error = %v", err)
+			}
+			if len(bodyResponses) != 1 || bodyResponses[0].GetResponseBody() == nil {
+				t.Fatalf("got %v, want a single response body response", bodyResponses)
+			}
+			if mutation := bodyResponses[0].GetResponseBody().GetResponse().GetBodyMutation(); mutation != nil {
+				t.Errorf("error body was mutated: %v", mutation)
+			}
+
+			if route.cacheKey != "" {
+				t.Errorf("route cache key = %q, want it cleared for an error", route.cacheKey)
+			}
+			if _, cached := s.cache.get("server1|echo|{}"); cached {
+				t.Error("backend error was cached")
+			}
+		})
+	}
+}
@@ -0,0 +1,82 @@
+package handlers
+
+// RoutingTable is the effective routing table, built from the same state HandleRequestBody routes with
+type RoutingTable struct {
+	Backends         []BackendRoute    `json:"backends"`
+	Canaries         []CanaryRoute     `json:"canaries"`
+	ResponseTimeouts map[string]string `json:"response_timeouts"` // backend target or prefixed tool -> timeout
+	RoutedMethods    []string          `json:"routed_methods"`    // every other method is served by the helper
+	HelperToolPrefix string            `json:"helper_tool_prefix"`
+	UnroutableTools  RouteFailureMode  `json:"unroutable_tools"`
+}
+
+// BackendRoute describes the names routed to one backend target and how the request is rewritten for it
+type BackendRoute struct {
+	Target         string `json:"target"`
+	ToolPrefix     string `json:"tool_prefix"`          // flat prefix, always routed
+	ToolGroup      string `json:"tool_group,omitempty"` // group namespace, preferred over the flat prefix
+	ResourcePrefix string `json:"resource_prefix"`
+	SessionHeader  string `json:"session_header"`
+	Maintenance    bool   `json:"maintenance,omitempty"`
+	Unavailable    bool   `json:"unavailable,omitempty"`
+}
+
+// CanaryRoute describes a share of tools/call traffic split off to a canary target
+type CanaryRoute struct {
+	Match        string `json:"match"`
+	Stable       string `json:"stable"`
+	CanaryTarget string `json:"canary_target"`
+	Percent      int    `json:"percent"`
+	Sticky       bool   `json:"sticky"`
+}
+
+// RoutingTable returns the routing table this server applies. Group namespaces win over flat prefixes and
+// the longest match wins, so a tool name goes to the backend whose longest group or prefix it starts with.
+func (s *Server) RoutingTable() RoutingTable {
+	table := RoutingTable{
+		Backends:         make([]BackendRoute, 0, len(serverConfigs)),
+		Canaries:         make([]CanaryRoute, 0, len(s.config.Canary.Rules)),
+		ResponseTimeouts: make(map[string]string, len(s.config.BackendResponseTimeouts)),
+		RoutedMethods:    []string{"tools/call", "resources/read", "prompts/get"},
+		HelperToolPrefix: helperToolPrefix,
+		UnroutableTools:  s.config.RouteFailureMode,
+	}
+
+	maintenance, _ := s.helper.(BackendMaintenance)
+	availability, _ := s.helper.(BackendAvailability)
+	for _, config := range serverConfigs {
+		route := BackendRoute{
+			Target:         config.target,
+			ToolPrefix:     config.prefix,
+			ToolGroup:      config.group,
+			ResourcePrefix: ResourcePrefix(config.target),
+			SessionHeader:  getSessionHeaderForTarget(config.target),
+		}
+		if maintenance != nil {
+			route.Maintenance = maintenance.IsBackendInMaintenance(config.target)
+		}
+		if availability != nil {
+			_, route.Unavailable = availability.BackendUnavailable(config.target)
+		}
+		table.Backends = append(table.Backends, route)
+	}
+
+	for _, rule := range s.config.Canary.Rules {
+		stable := rule.Match
+		if target := getRouteTargetFromTool(rule.Match); target != "" {
+			stable = target
+		}
+		table.Canaries = append(table.Canaries, CanaryRoute{
+			Match:        rule.Match,
+			Stable:       stable,
+			CanaryTarget: rule.CanaryTarget,
+			Percent:      rule.Percent,
+			Sticky:       s.config.Canary.Sticky,
+		})
+	}
+
+	for match, timeout := range s.config.BackendResponseTimeouts {
+		table.ResponseTimeouts[match] = timeout.String()
+	}
+	return table
+}
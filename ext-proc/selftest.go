@@ -0,0 +1,116 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	basepb "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
+	eppb "github.com/envoyproxy/go-control-plane/envoy/service/ext_proc/v3"
+)
+
+// SelfTestResult is what the request and response handlers did with one synthetic tools/call
+type SelfTestResult struct {
+	Target         string // x-mcp-server set for Envoy routing
+	Tool           string // Tool name in the rewritten body sent to the backend
+	SessionHeader  string // Header carrying the backend session
+	BackendSession string // Backend session injected on the routed request
+	ClientSession  string // Session the client sees after response reverse mapping
+	Rejected       string // Set when ext-proc answered the call itself instead of routing it
+}
+
+// SelfTestRoute runs a tools/call for toolName on helperSessionID through HandleRequestBody and then
+// HandleResponseHeaders, exactly as Envoy would drive them, and reports the routing decisions made
+func (s *Server) SelfTestRoute(ctx context.Context, helperSessionID, toolName string, arguments map[string]any) (*SelfTestResult, error) {
+	body, err := json.Marshal(map[string]any{
+		"jsonrpc": "2.0",
+		"id":      "selftest",
+		"method":  "tools/call",
+		"params":  map[string]any{"name": toolName, "arguments": arguments},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode tools/call: %w", err)
+	}
+	data, err := decodeRequestBody(body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode tools/call: %w", err)
+	}
+
+	requestHeaders := &eppb.HttpHeaders{Headers: &basepb.HeaderMap{Headers: []*basepb.HeaderValue{
+		{Key: sessionHeader, RawValue: []byte(helperSessionID)},
+		{Key: "content-type", RawValue: []byte("application/json")},
+	}}}
+	ctx = context.WithValue(ctx, requestHeadersKey{}, requestHeaders)
+
+	route := &routeState{}
+	responses, err := s.HandleRequestBody(ctx, data, route)
+	if err != nil {
+		return nil, fmt.Errorf("request body handler failed: %w", err)
+	}
+
+	result := &SelfTestResult{}
+	var mutation *eppb.HeaderMutation
+	var rewrittenBody []byte
+	for _, response := range responses {
+		if immediate := response.GetImmediateResponse(); immediate != nil {
+			result.Rejected = fmt.Sprintf("%d %s", immediate.GetStatus().GetCode(), immediate.GetBody())
+			return result, nil
+		}
+		common := response.GetRequestBody().GetResponse()
+		if common == nil {
+			common = response.GetRequestHeaders().GetResponse()
+		}
+		if common.GetHeaderMutation() != nil {
+			mutation = common.GetHeaderMutation()
+		}
+		if body := common.GetBodyMutation().GetBody(); body != nil {
+			rewrittenBody = body
+		}
+		if body := common.GetBodyMutation().GetStreamedResponse().GetBody(); body != nil {
+			rewrittenBody = body
+		}
+	}
+	if mutation == nil {
+		result.Rejected = "request was not routed"
+		return result, nil
+	}
+
+	result.SessionHeader = route.sessionHeader
+	for _, header := range mutation.GetSetHeaders() {
+		switch strings.ToLower(header.GetHeader().GetKey()) {
+		case serverHeader:
+			result.Target = string(header.GetHeader().GetRawValue())
+		case route.sessionHeader:
+			result.BackendSession = string(header.GetHeader().GetRawValue())
+		}
+	}
+
+	var rewritten struct {
+		Params struct {
+			Name string `json:"name"`
+		} `json:"params"`
+	}
+	if err := json.Unmarshal(rewrittenBody, &rewritten); err != nil {
+		return nil, fmt.Errorf("failed to decode rewritten body: %w", err)
+	}
+	result.Tool = rewritten.Params.Name
+
+	// Answer as a backend that names its session after the helper session, which is what reverse mapping expects
+	responseHeaders := &eppb.HttpHeaders{Headers: &basepb.HeaderMap{Headers: []*basepb.HeaderValue{
+		{Key: ":status", RawValue: []byte("200")},
+		{Key: route.sessionHeader, RawValue: []byte(route.target + "-session-" + helperSessionID)},
+	}}}
+	responses, err = s.HandleResponseHeaders(responseHeaders, route)
+	if err != nil {
+		return nil, fmt.Errorf("response headers handler failed: %w", err)
+	}
+	for _, response := range responses {
+		for _, header := range response.GetResponseHeaders().GetResponse().GetHeaderMutation().GetSetHeaders() {
+			if strings.ToLower(header.GetHeader().GetKey()) == sessionHeader {
+				result.ClientSession = string(header.GetHeader().GetRawValue())
+			}
+		}
+	}
+	return result, nil
+}
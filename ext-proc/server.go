@@ -1,12 +1,16 @@
 package handlers
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"io"
 	"log"
+	"log/slog"
 	"strings"
+	"time"
 
 	extProcPb "github.com/envoyproxy/go-control-plane/envoy/service/ext_proc/v3"
 	"google.golang.org/grpc/codes"
@@ -16,33 +20,230 @@ import (
 // SessionMapper interface to access session mappings
 type SessionMapper interface {
 	GetSessionMapping(helperSessionID string) (*SessionMapping, bool)
-	DumpAllSessions()
+	DumpAllSessions() []SessionMapping // snapshot of every mapping, counted when debugging mapping misses
+}
+
+// ToolSchemaLookup gives ext-proc access to the aggregated tool schemas.
+// It is optional - a SessionMapper that also implements it enables argument validation and caching.
+type ToolSchemaLookup interface {
+	GetToolRequiredArguments(toolName string) ([]string, bool)
+	IsToolReadOnly(toolName string) bool
+}
+
+// ToolVisibility restricts tools to allowlisted principals.
+// It is optional - a SessionMapper that also implements it has calls to hidden tools rejected.
+type ToolVisibility interface {
+	IsToolVisible(toolName, principal string) bool
+}
+
+// BackendMaintenance reports backends an operator has taken down for planned maintenance.
+// It is optional - a SessionMapper that also implements it has requests to those backends rejected.
+type BackendMaintenance interface {
+	IsBackendInMaintenance(backend string) bool
+}
+
+// BackendAvailability reports backends the helper started without because they failed to initialize.
+// It is optional - a SessionMapper that also implements it has requests to those backends rejected.
+type BackendAvailability interface {
+	BackendUnavailable(backend string) (reason string, unavailable bool)
+}
+
+// ToolRoutes is the helper's authoritative table of aggregated tools and the backends that listed them.
+// It is optional - a SessionMapper that also implements it has tool calls routed by exact name.
+type ToolRoutes interface {
+	ToolRoute(toolName string) (target, backendToolName string, ok bool)
+}
+
+// ToolTimeouts reports the response timeouts aggregated tools ask for in their annotations.
+// It is optional - a SessionMapper that also implements it has those timeouts applied to calls.
+type ToolTimeouts interface {
+	GetToolTimeout(toolName string) (time.Duration, bool)
 }
 
 // SessionMapping represents the mapping between helper and backend sessions
 type SessionMapping struct {
-	HelperSessionID  string
-	Server1SessionID string
-	Server2SessionID string
+	HelperSessionID string            `json:"helper_session"`
+	Principal       string            `json:"principal,omitempty"` // Authenticated principal that created the session, empty when auth is off
+	BackendSessions map[string]string `json:"backend_sessions"`    // Backend target -> session ID, empty for stateless backends - calls are routed without a session header
+}
+
+// RouteFailureMode controls how tool calls that can't be routed are handled
+type RouteFailureMode string
+
+const (
+	// RouteFailureOpen passes unroutable requests to the helper untouched
+	RouteFailureOpen RouteFailureMode = "open"
+	// RouteFailureClosed rejects unroutable requests with a clear error
+	RouteFailureClosed RouteFailureMode = "closed"
+)
+
+// ParseRouteFailureMode parses a failure mode, defaulting to closed for unknown values
+func ParseRouteFailureMode(mode string) RouteFailureMode {
+	switch RouteFailureMode(strings.ToLower(mode)) {
+	case RouteFailureOpen:
+		return RouteFailureOpen
+	case RouteFailureClosed:
+		return RouteFailureClosed
+	default:
+		log.Printf("[EXT-PROC] ⚠️ Unknown route failure mode %q, defaulting to %s", mode, RouteFailureClosed)
+		return RouteFailureClosed
+	}
+}
+
+// ProcessingPhases selects which phases of an exchange an ext-proc instance owns,
+// so request-side and response-side processing can run as separate filters
+type ProcessingPhases string
+
+const (
+	// PhasesAll processes both the request and the response
+	PhasesAll ProcessingPhases = "all"
+	// PhasesRequestOnly routes requests and passes responses through untouched
+	PhasesRequestOnly ProcessingPhases = "request"
+	// PhasesResponseOnly maps response sessions and passes requests through untouched
+	PhasesResponseOnly ProcessingPhases = "response"
+)
+
+// ParseProcessingPhases parses processing phases, defaulting to all for unknown values
+func ParseProcessingPhases(phases string) ProcessingPhases {
+	switch ProcessingPhases(strings.ToLower(phases)) {
+	case PhasesAll:
+		return PhasesAll
+	case PhasesRequestOnly:
+		return PhasesRequestOnly
+	case PhasesResponseOnly:
+		return PhasesResponseOnly
+	default:
+		log.Printf("[EXT-PROC] ⚠️ Unknown processing phases %q, defaulting to %s", phases, PhasesAll)
+		return PhasesAll
+	}
+}
+
+// Config holds the ext-proc behaviour settings
+type Config struct {
+	Phases               ProcessingPhases // Which phases this instance owns, the rest are no-ops
+	RouteFailureMode     RouteFailureMode // How unroutable tool calls are handled
+	ValidateRequiredArgs bool             // Reject tool calls missing required arguments before routing
+	ResponseCacheTTL     time.Duration    // Cache read-only tool results for this long, 0 disables caching
+	ResponseCacheSize    int              // Most results cached at once, least recently used evicted first; 0 is unbounded
+	Canary               CanaryConfig     // Percentage-based routing to canary targets
+	DebugLogging         bool             // Log every routing step, not just the per-request routing event
+
+	// Add x-mcp-backend-duration-ms to routed responses, for telling gateway overhead from backend time
+	BackendDurationHeader bool
+
+	// Route requests whose jsonrpc version is missing or not "2.0" as if it were "2.0"
+	LenientJSONRPC bool
+
+	// Fields redacted wherever they appear before request and response bodies are logged
+	RedactFields []string
+
+	// Add x-mcp-original-toolname to routed requests, the aggregated name before the prefix was stripped
+	OriginalToolNameHeader bool
+
+	// What to do with tool results that don't match the tool's declared output schema
+	OutputSchemaValidation OutputSchemaValidation
+
+	// Content-type set on rewritten tool call bodies sent to backends, empty keeps the client's
+	BackendContentType string
+
+	// What to do with client notifications the helper doesn't handle
+	UnknownNotifications UnknownNotificationPolicy
+
+	// Records tool calls that fail routing, nil disables the dead-letter log
+	DeadLetters *DeadLetterLog
+
+	// Limit on backend response time per target or prefixed tool name, enforced by Envoy.
+	// A tool's own entry overrides its backend's, as does a timeoutMs annotation the tool declares, for inherently slow tools.
+	BackendResponseTimeouts map[string]time.Duration
+
+	// Checks the credentials on routed requests against the principal that owns the session, nil disables it
+	Authenticator Authenticator
+
+	// Tool calls per second allowed per principal (or session when unauthenticated), 0 disables limiting
+	RateLimit      float64
+	RateLimitBurst int
+
+	// Limits on incoming request headers, answered with 431; 0 disables a limit
+	MaxRequestHeaders     int
+	MaxRequestHeaderBytes int
+
+	// Backend response status -> status sent to the client, applied to routed responses
+	StatusRemaps map[int]StatusRemap
+}
+
+// ParseBackendResponseTimeouts parses timeouts of the form "<target-or-tool>=<duration>,..."
+func ParseBackendResponseTimeouts(spec string) (map[string]time.Duration, error) {
+	timeouts := make(map[string]time.Duration)
+	for _, entry := range strings.Split(spec, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		target, durationStr, ok := strings.Cut(entry, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid backend timeout %q: expected <target>=<duration>", entry)
+		}
+		timeout, err := time.ParseDuration(strings.TrimSpace(durationStr))
+		if err != nil {
+			return nil, fmt.Errorf("invalid backend timeout %q: %w", entry, err)
+		}
+		timeouts[strings.TrimSpace(target)] = timeout
+	}
+	return timeouts, nil
 }
 
-func NewServer(streaming bool, helper SessionMapper) *Server {
-	return &Server{
+func NewServer(streaming bool, helper SessionMapper, config Config) *Server {
+	s := &Server{
 		streaming: streaming,
 		helper:    helper,
+		config:    config,
+		redact:    newRedactor(config.RedactFields),
 	}
+	if config.ResponseCacheTTL > 0 {
+		s.cache = newResponseCache(config.ResponseCacheTTL, config.ResponseCacheSize)
+	}
+	if config.RateLimit > 0 {
+		s.rateLimiter = NewRateLimiter(config.RateLimit, config.RateLimitBurst)
+	}
+	return s
 }
 
 // Server implements the Envoy external processing server.
 // https://www.envoyproxy.io/docs/envoy/latest/api-v3/service/ext_proc/v3/external_processor.proto
 type Server struct {
-	streaming      bool
-	requestHeaders *extProcPb.HttpHeaders // Store headers for later use in body processing
-	helper         SessionMapper          // Direct access to session mappings
+	streaming   bool
+	helper      SessionMapper // Direct access to session mappings
+	config      Config
+	cache       *responseCache // nil when response caching is disabled
+	rateLimiter *RateLimiter   // nil when rate limiting is disabled
+	redact      redactor       // applied to bodies before they are logged
 }
 
 const RequestIdHeaderKey = "x-request-id"
 
+// headerLimitExceeded returns why a request's headers break the configured limits, or "" when they don't
+func (s *Server) headerLimitExceeded(headers *extProcPb.HttpHeaders) string {
+	headerValues := headers.GetHeaders().GetHeaders()
+	if s.config.MaxRequestHeaders > 0 && len(headerValues) > s.config.MaxRequestHeaders {
+		return fmt.Sprintf("Request has %d headers, the limit is %d", len(headerValues), s.config.MaxRequestHeaders)
+	}
+	if s.config.MaxRequestHeaderBytes > 0 {
+		size := 0
+		for _, header := range headerValues {
+			size += len(header.GetKey()) + len(header.GetValue()) + len(header.GetRawValue())
+		}
+		if size > s.config.MaxRequestHeaderBytes {
+			return fmt.Sprintf("Request headers total %d bytes, the limit is %d", size, s.config.MaxRequestHeaderBytes)
+		}
+	}
+	return ""
+}
+
+// requestHeadersKey is the context key for the request headers of the current stream.
+// Headers are kept per stream because a single Server handles many concurrent streams.
+type requestHeadersKey struct{}
+
 func extractHeaderValue(req *extProcPb.ProcessingRequest_RequestHeaders, headerKey string) string {
 	// header key should be case insensitive
 	headerKeyInLower := strings.ToLower(headerKey)
@@ -57,9 +258,10 @@ func extractHeaderValue(req *extProcPb.ProcessingRequest_RequestHeaders, headerK
 }
 func (s *Server) Process(srv extProcPb.ExternalProcessor_ProcessServer) error {
 	ctx := srv.Context()
-	log.Println("Processing new request")
+	s.debugf("Processing new request")
 
 	streamedBody := &streamedBody{}
+	route := &routeState{}
 
 	for {
 		select {
@@ -81,8 +283,19 @@ func (s *Server) Process(srv extProcPb.ExternalProcessor_ProcessServer) error {
 		var err error
 		switch v := req.Request.(type) {
 		case *extProcPb.ProcessingRequest_RequestHeaders:
-			// Store headers for later use in body processing
-			s.requestHeaders = req.GetRequestHeaders()
+			if !s.ownsRequestPhase() {
+				responses = noopResponse(req)
+				break
+			}
+
+			// Oversized header sets are rejected before anything iterates or keeps them
+			if reason := s.headerLimitExceeded(req.GetRequestHeaders()); reason != "" {
+				responses = s.createErrorResponse(reason, 431)
+				break
+			}
+
+			// Store headers on the stream context for later use in body processing
+			ctx = context.WithValue(ctx, requestHeadersKey{}, req.GetRequestHeaders())
 
 			if s.streaming && !req.GetRequestHeaders().GetEndOfStream() {
 				// If streaming and the body is not empty, then headers are handled when processing request body.
@@ -94,12 +307,24 @@ func (s *Server) Process(srv extProcPb.ExternalProcessor_ProcessServer) error {
 				responses, err = s.HandleRequestHeaders(req.GetRequestHeaders())
 			}
 		case *extProcPb.ProcessingRequest_RequestBody:
-			log.Printf("Incoming body chunk: %s (EoS: %t)", string(v.RequestBody.Body), v.RequestBody.EndOfStream)
-			responses, err = s.processRequestBody(ctx, req.GetRequestBody(), streamedBody)
+			if !s.ownsRequestPhase() {
+				responses = noopResponse(req)
+				break
+			}
+			s.debugf("Incoming body chunk: %s (EoS: %t)", string(v.RequestBody.Body), v.RequestBody.EndOfStream)
+			responses, err = s.processRequestBody(ctx, req.GetRequestBody(), streamedBody, route)
 		case *extProcPb.ProcessingRequest_ResponseHeaders:
-			responses, err = s.HandleResponseHeaders(req.GetResponseHeaders())
+			if !s.ownsResponsePhase() {
+				responses = noopResponse(req)
+				break
+			}
+			responses, err = s.HandleResponseHeaders(req.GetResponseHeaders(), route)
 		case *extProcPb.ProcessingRequest_ResponseBody:
-			responses, err = s.HandleResponseBody(req.GetResponseBody())
+			if !s.ownsResponsePhase() {
+				responses = noopResponse(req)
+				break
+			}
+			responses, err = s.HandleResponseBody(req.GetResponseBody(), route)
 		default:
 			log.Printf("Unknown Request type: %T", v)
 			return status.Error(codes.Unknown, "unknown request type")
@@ -111,7 +336,7 @@ func (s *Server) Process(srv extProcPb.ExternalProcessor_ProcessServer) error {
 		}
 
 		for _, resp := range responses {
-			log.Printf("Response generated: %+v", resp)
+			s.debugf("Response generated: %+v", resp)
 			if err := srv.Send(resp); err != nil {
 				log.Printf("Send failed: %v", err)
 				return status.Errorf(codes.Unknown, "failed to send response back to Envoy: %v", err)
@@ -120,11 +345,82 @@ func (s *Server) Process(srv extProcPb.ExternalProcessor_ProcessServer) error {
 	}
 }
 
+// logger returns the structured logger for ext-proc events, tagged with the component
+func logger() *slog.Logger {
+	return slog.Default().With("component", "ext-proc")
+}
+
+// debugf logs verbose per-step processing details when debug logging is enabled
+func (s *Server) debugf(format string, args ...any) {
+	if s.config.DebugLogging {
+		log.Printf(format, args...)
+	}
+}
+
+// ownsRequestPhase reports whether this instance processes request headers and bodies
+func (s *Server) ownsRequestPhase() bool {
+	return s.config.Phases != PhasesResponseOnly
+}
+
+// ownsResponsePhase reports whether this instance processes response headers and bodies
+func (s *Server) ownsResponsePhase() bool {
+	return s.config.Phases != PhasesRequestOnly
+}
+
+// noopResponse returns a response that lets Envoy continue unchanged for a phase this instance doesn't own
+func noopResponse(req *extProcPb.ProcessingRequest) []*extProcPb.ProcessingResponse {
+	var resp *extProcPb.ProcessingResponse
+	switch req.Request.(type) {
+	case *extProcPb.ProcessingRequest_RequestHeaders:
+		resp = &extProcPb.ProcessingResponse{
+			Response: &extProcPb.ProcessingResponse_RequestHeaders{RequestHeaders: &extProcPb.HeadersResponse{}},
+		}
+	case *extProcPb.ProcessingRequest_RequestBody:
+		resp = &extProcPb.ProcessingResponse{
+			Response: &extProcPb.ProcessingResponse_RequestBody{RequestBody: &extProcPb.BodyResponse{}},
+		}
+	case *extProcPb.ProcessingRequest_ResponseHeaders:
+		resp = &extProcPb.ProcessingResponse{
+			Response: &extProcPb.ProcessingResponse_ResponseHeaders{ResponseHeaders: &extProcPb.HeadersResponse{}},
+		}
+	case *extProcPb.ProcessingRequest_ResponseBody:
+		resp = &extProcPb.ProcessingResponse{
+			Response: &extProcPb.ProcessingResponse_ResponseBody{ResponseBody: &extProcPb.BodyResponse{}},
+		}
+	default:
+		return nil
+	}
+	return []*extProcPb.ProcessingResponse{resp}
+}
+
 type streamedBody struct {
 	body []byte
 }
 
-func (s *Server) processRequestBody(ctx context.Context, body *extProcPb.HttpBody, streamedBody *streamedBody) ([]*extProcPb.ProcessingResponse, error) {
+// routeState carries the request-phase routing decision to the response phase of the same stream
+type routeState struct {
+	target        string    // backend the request was routed to, empty if handled by the helper
+	sessionHeader string    // header the backend uses to carry its session ID
+	cacheKey      string    // set when the backend response should be cached
+	routedAt      time.Time // when the routing decision was made, start of the backend duration
+	name          string    // aggregated tool, resource or prompt name the request was routed for
+	toolCall      bool      // set when the routed request was a tools/call, so name is a tool
+	helperSession string    // helper session the request was routed for
+}
+
+// decodeRequestBody decodes a JSON-RPC body keeping numbers as json.Number, so ids and arguments
+// are echoed and forwarded exactly as sent rather than round-tripped through float64
+func decodeRequestBody(body []byte) (map[string]any, error) {
+	var requestBody map[string]any
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.UseNumber()
+	if err := decoder.Decode(&requestBody); err != nil {
+		return nil, err
+	}
+	return requestBody, nil
+}
+
+func (s *Server) processRequestBody(ctx context.Context, body *extProcPb.HttpBody, streamedBody *streamedBody, route *routeState) ([]*extProcPb.ProcessingResponse, error) {
 
 	var requestBody map[string]interface{}
 	if s.streaming {
@@ -132,7 +428,8 @@ func (s *Server) processRequestBody(ctx context.Context, body *extProcPb.HttpBod
 		// In the stream case, we can receive multiple request bodies.
 		if body.EndOfStream {
 			log.Println("Flushing stream buffer")
-			err := json.Unmarshal(streamedBody.body, &requestBody)
+			var err error
+			requestBody, err = decodeRequestBody(streamedBody.body)
 			if err != nil {
 				log.Printf("Error unmarshaling request body: %v", err)
 			}
@@ -140,12 +437,14 @@ func (s *Server) processRequestBody(ctx context.Context, body *extProcPb.HttpBod
 			return nil, nil
 		}
 	} else {
-		if err := json.Unmarshal(body.GetBody(), &requestBody); err != nil {
+		var err error
+		requestBody, err = decodeRequestBody(body.GetBody())
+		if err != nil {
 			return nil, err
 		}
 	}
 
-	requestBodyResp, err := s.HandleRequestBody(ctx, requestBody)
+	requestBodyResp, err := s.HandleRequestBody(ctx, requestBody, route)
 	if err != nil {
 		return nil, err
 	}
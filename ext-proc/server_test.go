@@ -0,0 +1,78 @@
+package handlers
+
+import (
+	"context"
+	"testing"
+
+	basepb "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
+	eppb "github.com/envoyproxy/go-control-plane/envoy/service/ext_proc/v3"
+)
+
+// fakeHelper is a SessionMapper backed by a fixed set of sessions
+type fakeHelper struct {
+	sessions map[string]*SessionMapping
+}
+
+func (f *fakeHelper) GetSessionMapping(helperSessionID string) (*SessionMapping, bool) {
+	mapping, ok := f.sessions[helperSessionID]
+	return mapping, ok
+}
+
+func (f *fakeHelper) DumpAllSessions() []SessionMapping {
+	sessions := make([]SessionMapping, 0, len(f.sessions))
+	for _, mapping := range f.sessions {
+		sessions = append(sessions, *mapping)
+	}
+	return sessions
+}
+
+// newFakeHelper returns a helper with one session mapped to a backend session on each target
+func newFakeHelper(helperSessionID string, backendSessions map[string]string) *fakeHelper {
+	return &fakeHelper{sessions: map[string]*SessionMapping{
+		helperSessionID: {HelperSessionID: helperSessionID, BackendSessions: backendSessions},
+	}}
+}
+
+// useBackends replaces the registered backend targets for the duration of a test
+func useBackends(t *testing.T, configs ...serverConfig) {
+	t.Helper()
+
+	previous := serverConfigs
+	serverConfigs = nil
+	for _, config := range configs {
+		if config.sessionHeader == "" {
+			config.sessionHeader = defaultSessionHeader
+		}
+		serverConfigs = append(serverConfigs, config)
+	}
+	t.Cleanup(func() { serverConfigs = previous })
+}
+
+// requestContext returns a stream context carrying request headers with the helper session
+func requestContext(helperSessionID string) context.Context {
+	headers := &eppb.HttpHeaders{Headers: &basepb.HeaderMap{Headers: []*basepb.HeaderValue{
+		{Key: sessionHeader, RawValue: []byte(helperSessionID)},
+		{Key: "content-type", RawValue: []byte("application/json")},
+	}}}
+	return context.WithValue(context.Background(), requestHeadersKey{}, headers)
+}
+
+// decodeTestBody decodes a JSON-RPC body the way the gRPC stream does
+func decodeTestBody(t *testing.T, body string) map[string]any {
+	t.Helper()
+
+	data, err := decodeRequestBody([]byte(body))
+	if err != nil {
+		t.Fatalf("decoding %s: %v", body, err)
+	}
+	return data
+}
+
+// setHeaders flattens a header mutation into a name -> value map
+func setHeaders(mutation *eppb.HeaderMutation) map[string]string {
+	headers := make(map[string]string)
+	for _, option := range mutation.GetSetHeaders() {
+		headers[option.GetHeader().GetKey()] = string(option.GetHeader().GetRawValue())
+	}
+	return headers
+}
@@ -0,0 +1,100 @@
+package handlers
+
+import (
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+
+	basepb "github.com/envoyproxy/go-control-plane/envoy/config/core/v3"
+	eppb "github.com/envoyproxy/go-control-plane/envoy/service/ext_proc/v3"
+)
+
+// StatusRemap replaces a backend response status with the gateway's status for the same condition
+type StatusRemap struct {
+	Status     int `json:"status"`
+	RetryAfter int `json:"retry_after_seconds,omitempty"` // Retry-After hint sent with the remapped status, 0 for none
+}
+
+// ParseStatusRemaps parses remaps of the form "<backend-status>=<status>[:<retry-after-seconds>],...",
+// e.g. "502=503:5,504=503"
+func ParseStatusRemaps(spec string) (map[int]StatusRemap, error) {
+	remaps := make(map[int]StatusRemap)
+	for _, entry := range strings.Split(spec, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		fromStr, toStr, ok := strings.Cut(entry, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid status remap %q: expected <backend-status>=<status>[:<retry-after-seconds>]", entry)
+		}
+		from, err := parseStatusCode(fromStr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid status remap %q: %w", entry, err)
+		}
+
+		toStr, retryAfterStr, hasRetryAfter := strings.Cut(toStr, ":")
+		var remap StatusRemap
+		if remap.Status, err = parseStatusCode(toStr); err != nil {
+			return nil, fmt.Errorf("invalid status remap %q: %w", entry, err)
+		}
+		if hasRetryAfter {
+			remap.RetryAfter, err = strconv.Atoi(strings.TrimSpace(retryAfterStr))
+			if err != nil || remap.RetryAfter < 0 {
+				return nil, fmt.Errorf("invalid status remap %q: retry-after must be a number of seconds", entry)
+			}
+		}
+		remaps[from] = remap
+	}
+	return remaps, nil
+}
+
+// parseStatusCode parses an HTTP status code
+func parseStatusCode(value string) (int, error) {
+	status, err := strconv.Atoi(strings.TrimSpace(value))
+	if err != nil || status < 100 || status > 599 {
+		return 0, fmt.Errorf("%q is not an HTTP status code", value)
+	}
+	return status, nil
+}
+
+// remapResponseStatus returns the header mutations replacing a routed backend's response status
+// according to the remap table, or nil when the status is kept
+func (s *Server) remapResponseStatus(headers *eppb.HttpHeaders, route *routeState) []*basepb.HeaderValueOption {
+	if len(s.config.StatusRemaps) == 0 || route == nil || route.target == "" {
+		return nil
+	}
+
+	var status int
+	for _, header := range headers.GetHeaders().GetHeaders() {
+		if header.GetKey() == ":status" {
+			status, _ = strconv.Atoi(string(header.GetRawValue()))
+			break
+		}
+	}
+	remap, ok := s.config.StatusRemaps[status]
+	if !ok {
+		return nil
+	}
+
+	log.Printf("[EXT-PROC] Remapping %s response status %d to %d", route.target, status, remap.Status)
+	setHeaders := []*basepb.HeaderValueOption{
+		{
+			Header: &basepb.HeaderValue{
+				Key:      ":status",
+				RawValue: []byte(strconv.Itoa(remap.Status)),
+			},
+		},
+	}
+	if remap.RetryAfter > 0 {
+		setHeaders = append(setHeaders, &basepb.HeaderValueOption{
+			Header: &basepb.HeaderValue{
+				Key:      "retry-after",
+				RawValue: []byte(strconv.Itoa(remap.RetryAfter)),
+			},
+		})
+	}
+	return setHeaders
+}
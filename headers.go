@@ -0,0 +1,50 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+)
+
+// Limits on incoming request headers, answered with 431 Request Header Fields Too Large; 0 disables a limit
+var (
+	maxRequestHeaders     = getEnvInt("MAX_REQUEST_HEADERS", 100)
+	maxRequestHeaderBytes = getEnvInt("MAX_REQUEST_HEADER_BYTES", 64*1024)
+)
+
+// headerLimitMiddleware rejects requests whose headers exceed the configured count or total size,
+// before authentication, logging or any handler looks at them
+func headerLimitMiddleware(maxHeaders, maxBytes int, next http.Handler) http.Handler {
+	if maxHeaders <= 0 && maxBytes <= 0 {
+		return next
+	}
+	log.Printf("Limiting request headers to %d headers and %d bytes", maxHeaders, maxBytes)
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if reason := headerLimitExceeded(r.Header, maxHeaders, maxBytes); reason != "" {
+			log.Printf("🚫 Rejecting %s %s from %s: %s", r.Method, r.URL.Path, r.RemoteAddr, reason)
+			http.Error(w, reason, http.StatusRequestHeaderFieldsTooLarge)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// headerLimitExceeded returns why headers break the limits, or "" when they don't.
+// Repeated headers count once per value, as they do on the wire.
+func headerLimitExceeded(header http.Header, maxHeaders, maxBytes int) string {
+	count, size := 0, 0
+	for name, values := range header {
+		count += len(values)
+		for _, value := range values {
+			size += len(name) + len(value)
+		}
+	}
+	if maxHeaders > 0 && count > maxHeaders {
+		return fmt.Sprintf("Request has %d headers, the limit is %d", count, maxHeaders)
+	}
+	if maxBytes > 0 && size > maxBytes {
+		return fmt.Sprintf("Request headers total %d bytes, the limit is %d", size, maxBytes)
+	}
+	return ""
+}
@@ -0,0 +1,203 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mark3labs/mcp-go/client"
+	"github.com/mark3labs/mcp-go/client/transport"
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// Backends that must be healthy for /readyz to report ready, comma-separated. Empty means every
+// backend not marked optional, so partial deployments can stay ready while non-critical backends are down.
+var readinessRequiredBackends = getEnv("READINESS_REQUIRED_BACKENDS", "")
+
+// backendHealth caches the outcome of the background backend checks so probes are cheap
+type backendHealth struct {
+	mu        sync.RWMutex
+	checkedAt time.Time         // end of the last full round of checks, zero before the first
+	errors    map[string]string // backend name -> reason it was unreachable in the last round
+}
+
+// readiness is the /readyz response body
+type readiness struct {
+	Ready       bool              `json:"ready"`
+	CheckedAt   *time.Time        `json:"checked_at,omitempty"`
+	Failing     []string          `json:"failing,omitempty"` // required backends keeping the helper unready
+	Unreachable map[string]string `json:"unreachable,omitempty"`
+}
+
+// parseReadinessRequired returns the names of the backends readiness depends on, from
+// READINESS_REQUIRED_BACKENDS or, when that's empty, every backend not marked optional
+func parseReadinessRequired(spec string, backends []BackendConfig) (map[string]bool, error) {
+	required := make(map[string]bool)
+	if strings.TrimSpace(spec) == "" {
+		for _, backend := range backends {
+			if !backend.Optional {
+				required[backend.Name] = true
+			}
+		}
+		return required, nil
+	}
+
+	for _, name := range strings.Split(spec, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		if _, ok := findBackendIn(backends, name); !ok {
+			names := make([]string, 0, len(backends))
+			for _, backend := range backends {
+				names = append(names, backend.Name)
+			}
+			return nil, fmt.Errorf("unknown backend %q, configured backends are %s", name, strings.Join(names, ","))
+		}
+		required[name] = true
+	}
+	return required, nil
+}
+
+// StartHealthChecks pings every backend every interval until ctx is cancelled, caching the results for /readyz
+func (g *MCPHelper) StartHealthChecks(ctx context.Context, interval time.Duration) {
+	log.Printf("Checking backend health every %s", interval)
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			g.checkBackendHealth(ctx)
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// checkBackendHealth runs one round of checks against all backends in parallel
+func (g *MCPHelper) checkBackendHealth(ctx context.Context) {
+	results := make([]error, len(g.backends))
+	var wg sync.WaitGroup
+	for i, backend := range g.backends {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			checkCtx, cancel := context.WithTimeout(ctx, g.timeouts.Discovery)
+			defer cancel()
+			results[i] = pingBackend(checkCtx, backend)
+		}()
+	}
+	wg.Wait()
+
+	unreachable := make(map[string]string)
+	for i, backend := range g.backends {
+		if results[i] != nil {
+			unreachable[backend.Name] = results[i].Error()
+		}
+	}
+
+	g.health.mu.Lock()
+	for name := range unreachable {
+		if _, already := g.health.errors[name]; !already {
+			log.Printf("⚠️ Backend %s is unreachable: %s", name, unreachable[name])
+		}
+	}
+	for name := range g.health.errors {
+		if _, still := unreachable[name]; !still {
+			log.Printf("✅ Backend %s is reachable again", name)
+		}
+	}
+	g.health.errors = unreachable
+	g.health.checkedAt = time.Now()
+	g.health.mu.Unlock()
+}
+
+// pingBackend initializes a fresh connection to a backend and pings it
+func pingBackend(ctx context.Context, backend BackendConfig) error {
+	httpTransport, err := transport.NewStreamableHTTP(backend.URL)
+	if err != nil {
+		return fmt.Errorf("failed to create transport: %w", err)
+	}
+	pingClient := client.NewClient(httpTransport)
+	defer pingClient.Close()
+
+	initRequest := mcp.InitializeRequest{}
+	initRequest.Params.ProtocolVersion = mcp.LATEST_PROTOCOL_VERSION
+	initRequest.Params.ClientInfo = mcp.Implementation{
+		Name:    "MCP Helper (Health)",
+		Version: "1.0.0",
+	}
+	applyInitParams(backend, &initRequest.Params)
+	if _, err := pingClient.Initialize(ctx, initRequest); err != nil {
+		return fmt.Errorf("initialize failed: %w", err)
+	}
+	if err := pingClient.Ping(ctx); err != nil {
+		return fmt.Errorf("ping failed: %w", err)
+	}
+	return nil
+}
+
+// handleHealthz reports that the process is up, without touching the backends
+func handleHealthz(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain")
+	fmt.Fprintln(w, "ok")
+}
+
+// handleReadyz reports ready once every required backend answered the most recent round of checks,
+// and that round is recent. Other backends are listed when unreachable but don't affect readiness.
+func (g *MCPHelper) handleReadyz(interval time.Duration, required map[string]bool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		g.health.mu.RLock()
+		checkedAt := g.health.checkedAt
+		unreachable := make(map[string]string, len(g.health.errors))
+		for name, reason := range g.health.errors {
+			unreachable[name] = reason
+		}
+		g.health.mu.RUnlock()
+
+		status := readiness{Ready: true, Unreachable: unreachable}
+		switch {
+		case checkedAt.IsZero():
+			status.Ready = false
+			for _, backend := range g.backends {
+				unreachable[backend.Name] = "not checked yet"
+			}
+		case time.Since(checkedAt) > 3*interval:
+			status.Ready = false
+			status.CheckedAt = &checkedAt
+			for _, backend := range g.backends {
+				if _, down := unreachable[backend.Name]; !down {
+					unreachable[backend.Name] = "health status is stale"
+				}
+			}
+		default:
+			status.CheckedAt = &checkedAt
+		}
+
+		for _, backend := range g.backends {
+			if _, down := unreachable[backend.Name]; down && required[backend.Name] {
+				status.Failing = append(status.Failing, backend.Name)
+			}
+		}
+		if len(status.Failing) > 0 {
+			status.Ready = false
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if !status.Ready {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+		if err := json.NewEncoder(w).Encode(status); err != nil {
+			log.Printf("Failed to write readiness: %v", err)
+		}
+	}
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	extProc "mcp-helper/ext-proc"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// handleHelperCall handles the helper_call tool, calling a backend tool by backend and backend tool name
+// rather than by its aggregated name. Helper tools are never routed by ext-proc, so the call is forwarded
+// over the session's own backend connection whether or not Envoy is in the path, with ext-proc's tool
+// call policies applied by forwardToolCall.
+func (g *MCPHelper) handleHelperCall(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	backend, err := req.RequireString("backend")
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	tool, err := req.RequireString("tool")
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	var arguments map[string]any
+	if raw, ok := req.GetArguments()["arguments"]; ok && raw != nil {
+		if arguments, ok = raw.(map[string]any); !ok {
+			return mcp.NewToolResultError("arguments must be an object"), nil
+		}
+	}
+
+	// The backend must have listed the tool, and the caller must be allowed to see it under its aggregated name
+	toolName := extProc.ToolPrefix(backend) + tool
+	g.toolsLock.RLock()
+	owner := g.toolBackends[toolName]
+	g.toolsLock.RUnlock()
+	if _, known := g.findBackend(backend); !known || owner != backend || !g.IsToolVisible(toolName, principalFromContext(ctx)) {
+		message := fmt.Sprintf("Unknown tool %s on backend %s", tool, backend)
+		if !known {
+			message = fmt.Sprintf("Unknown backend: %s", backend)
+		}
+		// Dead-lettered like a tools/call ext-proc can't route
+		var helperSession string
+		if session := server.ClientSessionFromContext(ctx); session != nil {
+			helperSession = session.SessionID()
+		}
+		g.toolCallPolicies.RecordForwardedFailure(helperSession, map[string]any{
+			"jsonrpc": mcp.JSONRPC_VERSION,
+			"method":  string(mcp.MethodToolsCall),
+			"params":  map[string]any{"name": toolName, "arguments": arguments},
+		}, message, 404)
+		return mcp.NewToolResultError(message), nil
+	}
+
+	backendReq := mcp.CallToolRequest{}
+	backendReq.Params.Name = toolName
+	backendReq.Params.Arguments = arguments
+	backendReq.Params.Meta = req.Params.Meta
+	return g.forwardToolCall(ctx, toolName, backendReq)
+}
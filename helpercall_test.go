@@ -0,0 +1,62 @@
+package main
+
+import (
+	"context"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	extProc "mcp-helper/ext-proc"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+func TestHelperCallAppliesToolCallPolicies(t *testing.T) {
+	backend := newTestBackend(t, server.ServerTool{
+		Tool: mcp.NewTool("echo", mcp.WithString("text", mcp.Required())),
+		Handler: func(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+			return mcp.NewToolResultText(req.GetString("text", "")), nil
+		},
+	})
+	config := testBackendConfig("server1", backend.URL)
+	if err := registerBackendRoutes([]BackendConfig{config}); err != nil {
+		t.Fatalf("registerBackendRoutes() error = %v", err)
+	}
+	helper := newTestHelper(t, config)
+	if err := helper.initializeBackends(); err != nil {
+		t.Fatalf("initializeBackends() error = %v", err)
+	}
+	helper.toolCallPolicies = extProc.NewServer(false, helper, extProc.Config{ValidateRequiredArgs: true})
+	helperServer := httptest.NewServer(helper.mcpHandler())
+	defer helperServer.Close()
+
+	mcpClient := connectTestClient(t, helperServer.URL)
+	waitForSessionMapping(t, helper, clientSessionID(t, mcpClient))
+
+	tests := []struct {
+		name      string
+		arguments map[string]any
+		wantError bool
+	}{
+		{name: "missing required argument", arguments: map[string]any{}, wantError: true},
+		{name: "valid call", arguments: map[string]any{"text": "hi"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+
+			request := mcp.CallToolRequest{}
+			request.Params.Name = "helper_call"
+			request.Params.Arguments = map[string]any{"backend": "server1", "tool": "echo", "arguments": tt.arguments}
+			result, err := mcpClient.CallTool(ctx, request)
+			if err != nil {
+				t.Fatalf("CallTool() error = %v", err)
+			}
+			if result.IsError != tt.wantError {
+				t.Errorf("result = %+v, want isError %t", result.Content, tt.wantError)
+			}
+		})
+	}
+}
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// validateInitParams checks every backend's <NAME>_INIT_PARAMS is a JSON object of initialize params
+// the client can send, e.g. {"capabilities":{"experimental":{"feature":{}}}}
+func validateInitParams(backends []BackendConfig) error {
+	for _, backend := range backends {
+		extra := backend.InitParams
+		if extra == "" {
+			continue
+		}
+		var params mcp.InitializeParams
+		decoder := json.NewDecoder(bytes.NewReader([]byte(extra)))
+		decoder.DisallowUnknownFields()
+		if err := decoder.Decode(&params); err != nil {
+			return fmt.Errorf("invalid initialize params for %s (only protocolVersion, capabilities and clientInfo can be set): %w", backend.Name, err)
+		}
+		log.Printf("Extra initialize params for %s: %s", backend.Name, extra)
+	}
+	return nil
+}
+
+// applyInitParams merges a backend's extra initialize params over the defaults;
+// nested objects such as experimental capabilities are merged key by key
+func applyInitParams(backend BackendConfig, params *mcp.InitializeParams) {
+	extra := backend.InitParams
+	if extra == "" {
+		return
+	}
+	if err := json.Unmarshal([]byte(extra), params); err != nil {
+		// Validated at startup, so this only happens if the config changed underneath us
+		log.Printf("⚠️ Ignoring invalid initialize params for %s: %v", backend.Name, err)
+	}
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+)
+
+// sensitiveHeaders carry credentials and are redacted in header dumps
+var sensitiveHeaders = map[string]bool{
+	"Authorization":       true,
+	"Proxy-Authorization": true,
+	"Cookie":              true,
+	"Set-Cookie":          true,
+}
+
+// loggedHeaderValue returns the value to log for a header, redacting credentials
+func loggedHeaderValue(name, value string) string {
+	if sensitiveHeaders[http.CanonicalHeaderKey(name)] {
+		return redacted
+	}
+	return value
+}
+
+// setupLogging installs the process-wide slog logger. "text" writes key=value lines for local development,
+// "json" writes one JSON object per line for log aggregators. log.Printf output goes through the same
+// logger at info level, so LOG_LEVEL filters it too and older lines become records with only a message
+// until they gain fields of their own.
+func setupLogging(format, level string) error {
+	var minLevel slog.Level
+	if err := minLevel.UnmarshalText([]byte(level)); err != nil {
+		return fmt.Errorf("invalid LOG_LEVEL %q: %w", level, err)
+	}
+
+	options := &slog.HandlerOptions{Level: minLevel}
+	switch format {
+	case "text":
+		slog.SetDefault(slog.New(slog.NewTextHandler(os.Stderr, options)))
+	case "json":
+		slog.SetDefault(slog.New(slog.NewJSONHandler(os.Stderr, options)))
+	default:
+		return fmt.Errorf("invalid log format %q: must be text or json", format)
+	}
+	return nil
+}
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"log"
+	"log/slog"
+	"os"
+	"testing"
+)
+
+func TestLoggedHeaderValueRedactsCredentials(t *testing.T) {
+	for _, name := range []string{"Authorization", "authorization", "Cookie", "Proxy-Authorization", "set-cookie"} {
+		if got := loggedHeaderValue(name, "secret"); got != redacted {
+			t.Errorf("%s: got %q, want it redacted", name, got)
+		}
+	}
+	if got := loggedHeaderValue("Content-Type", "application/json"); got != "application/json" {
+		t.Errorf("Content-Type: got %q", got)
+	}
+}
+
+func TestTextLoggingFiltersLogPrintf(t *testing.T) {
+	previous := slog.Default()
+	defer func() {
+		slog.SetDefault(previous)
+		log.SetOutput(os.Stderr)
+		log.SetFlags(log.LstdFlags)
+	}()
+
+	if err := setupLogging("text", "warn"); err != nil {
+		t.Fatal(err)
+	}
+	if slog.Default().Enabled(context.Background(), slog.LevelInfo) {
+		t.Fatal("info records are enabled at LOG_LEVEL=warn")
+	}
+
+	// log.Printf now goes through the text handler, which drops it below warn
+	var buf bytes.Buffer
+	slog.SetDefault(slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{Level: slog.LevelWarn})))
+	log.Printf("routine detail")
+	if buf.Len() != 0 {
+		t.Fatalf("log.Printf output was not filtered: %q", buf.String())
+	}
+}
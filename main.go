@@ -1,18 +1,30 @@
 package main
 
 import (
+	"bytes"
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
 	"flag"
 	"fmt"
+	"io"
 	"log"
+	"log/slog"
+	"maps"
 	"net"
 	"net/http"
 	"os"
 	"os/signal"
+	"sort"
+	"strconv"
+	"strings"
 	"sync"
 	"syscall"
 	"time"
 
+	configFile "mcp-helper/config"
 	extProc "mcp-helper/ext-proc"
 
 	extProcPb "github.com/envoyproxy/go-control-plane/envoy/service/ext_proc/v3"
@@ -20,7 +32,11 @@ import (
 	"github.com/mark3labs/mcp-go/client/transport"
 	"github.com/mark3labs/mcp-go/mcp"
 	"github.com/mark3labs/mcp-go/server"
+	"golang.org/x/net/netutil"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/keepalive"
 	"google.golang.org/grpc/reflection"
 )
 
@@ -32,28 +48,162 @@ func getEnv(key, defaultValue string) string {
 	return defaultValue
 }
 
-// Backend server configuration
+// getEnvInt gets an integer environment variable or returns a default value
+func getEnvInt(key string, defaultValue int) int {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+	parsed, err := strconv.Atoi(value)
+	if err != nil {
+		log.Printf("⚠️ Invalid integer for %s=%q, using default %d", key, value, defaultValue)
+		return defaultValue
+	}
+	return parsed
+}
+
+// getEnvFloat gets a floating point environment variable or returns a default value
+func getEnvFloat(key string, defaultValue float64) float64 {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+	parsed, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		log.Printf("⚠️ Invalid number for %s=%q, using default %g", key, value, defaultValue)
+		return defaultValue
+	}
+	return parsed
+}
+
+// getEnvDuration gets a duration environment variable (e.g. "10s") or returns a default value
+func getEnvDuration(key string, defaultValue time.Duration) time.Duration {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+	parsed, err := time.ParseDuration(value)
+	if err != nil {
+		log.Printf("⚠️ Invalid duration for %s=%q, using default %s", key, value, defaultValue)
+		return defaultValue
+	}
+	return parsed
+}
+
+// ext-proc gRPC server limits
 var (
-	server1URL = getEnv("SERVER1_URL", "http://localhost:8081")
-	server2URL = getEnv("SERVER2_URL", "http://localhost:8082")
+	// Maximum concurrent ext-proc streams per Envoy connection, aligned with the HTTP connection limit
+	grpcMaxConcurrentStreams = getEnvInt("GRPC_MAX_CONCURRENT_STREAMS", 1000)
+
+	// Minimum interval Envoy may send keepalive pings before the connection is closed for abuse
+	grpcKeepaliveMinTime = getEnvDuration("GRPC_KEEPALIVE_MIN_TIME", 10*time.Second)
+)
+
+// Routing and backend connection configuration
+var (
+	// How ext-proc handles tool calls it can't route: "open" passes them to the helper, "closed" rejects them
+	routeFailureMode = getEnv("ROUTE_FAILURE_MODE", "closed")
+
+	// Reject tool calls missing backend-declared required arguments before they are routed
+	validateRequiredArgs = getEnv("VALIDATE_REQUIRED_ARGS", "false") == "true"
+
+	// Route requests from clients that omit or misstate the jsonrpc version instead of leaving them to the helper
+	lenientJSONRPC = getEnv("LENIENT_JSONRPC", "false") == "true"
+
+	// Which phases this ext-proc instance owns: "all", "request" or "response" for split deployments
+	extProcPhases = getEnv("EXT_PROC_PHASES", "all")
+
+	// Canary routing rules "<target-or-tool>=<canary-target>:<percent>,..." and whether assignment sticks to the session
+	canaryRoutes = getEnv("CANARY_ROUTES", "")
+	canarySticky = getEnv("CANARY_STICKY", "true") == "true"
+
+	// Log level: "info" logs one routing event per request, "debug" adds every processing step and request
+	// header dumps, "warn" and "error" quieten it; -log-level overrides it
+	logLevel = getEnv("LOG_LEVEL", "info")
+
+	// Log output format, "text" for local development or "json" for log aggregators; -log-format overrides it
+	logFormat = getEnv("LOG_FORMAT", "text")
+
+	// Limit on tool execution time per backend or prefixed tool "<target-or-tool>=<duration>,...",
+	// e.g. "server1=30s,server2=10s,server1-long_job=5m" - a tool's entry overrides its backend's, as does
+	// a "timeoutMs" annotation the tool declares
+	backendResponseTimeouts = getEnv("BACKEND_RESPONSE_TIMEOUTS", "")
+
+	// Backend response statuses remapped for clients "<backend-status>=<status>[:<retry-after-seconds>],...",
+	// e.g. "502=503:5,504=503" so backends with different error conventions present one gateway contract
+	statusRemapSpec = getEnv("STATUS_REMAP", "")
+
+	// New sessions per second per principal (source IP when unauthenticated), 0 disables limiting
+	sessionRateLimit      = getEnvFloat("SESSION_RATE_LIMIT", 0)
+	sessionRateLimitBurst = getEnvInt("SESSION_RATE_LIMIT_BURST", 5)
+
+	// Proxies in front of the helper that append to X-Forwarded-For (Envoy by default), 0 to ignore the header.
+	// Hops left of the ones they appended are set by the client and never trusted.
+	trustedProxyHops = getEnvInt("TRUSTED_PROXY_HOPS", 1)
+
+	// Backends queried at once during tool aggregation and refreshes, 0 for all at once
+	discoveryConcurrency = getEnvInt("DISCOVERY_CONCURRENCY", 4)
+
+	// Concurrent backend initializes per backend during session creation, 0 for unlimited
+	backendInitConcurrency = getEnvInt("BACKEND_INIT_CONCURRENCY", 20)
+
+	// Tool calls per second per principal (session when unauthenticated), 0 disables rate limiting
+	rateLimit      = getEnvFloat("RATE_LIMIT", 0)
+	rateLimitBurst = getEnvInt("RATE_LIMIT_BURST", 10)
+
+	// Add x-mcp-backend-duration-ms to routed responses, for debugging where call latency comes from
+	backendDurationHeader = getEnv("BACKEND_DURATION_HEADER", "false") == "true"
+
+	// Send the prefixed tool name the client called to backends in x-mcp-original-toolname, for backend-side debugging
+	originalToolNameHeader = getEnv("ORIGINAL_TOOLNAME_HEADER", "false") == "true"
+
+	// Content-type for rewritten tool calls sent to backends, "preserve" keeps the client's
+	backendContentType = getEnv("BACKEND_CONTENT_TYPE", "application/json")
+
+	// What ext-proc does with client notifications the helper doesn't handle: "drop", "broadcast" or "error"
+	unknownNotificationPolicy = getEnv("UNKNOWN_NOTIFICATION_POLICY", "drop")
+
+	// Where ext-proc records tool calls that fail routing ("-" for stdout), empty disables the dead-letter log
+	deadLetterLog = getEnv("DEAD_LETTER_LOG", "")
+
+	// Request and response fields redacted wherever they appear before bodies are logged
+	redactFields = getEnv("REDACT_FIELDS", "password,token,secret,api_key,apikey,authorization")
+
+	// How long ext-proc caches results of read-only tools, 0 disables caching
+	responseCacheTTL = getEnvDuration("RESPONSE_CACHE_TTL", 0)
+
+	// Most tool results the response cache holds, evicting the least recently used; 0 is unbounded
+	responseCacheSize = getEnvInt("RESPONSE_CACHE_SIZE", 1000)
+
+	// Defer tool discovery from startup to the first client, for fast cold starts
+	lazyInit = getEnv("LAZY_INIT", "false") == "true"
+
+	// Forward tool calls to backends from the helper itself when Envoy isn't in the path
+	standaloneMode = getEnv("STANDALONE_MODE", "false") == "true"
+
+	// Hold a GET event stream open on each backend session, so notifications the backend sends
+	// outside of a request (such as tools/list_changed) reach the helper
+	backendNotificationStream = getEnv("BACKEND_NOTIFICATION_STREAM", "true") == "true"
+
+	// Start without backends that fail to initialize, rather than failing startup, and serve the healthy ones
+	degradedStartup = getEnv("DEGRADED_STARTUP", "false") == "true"
 )
 
 // ClientBackendConnections holds the backend client connections for a specific client session
 type ClientBackendConnections struct {
-	ClientSessionID  string
-	Server1Client    *client.Client
-	Server2Client    *client.Client
-	Server1SessionID string // Tracked session ID for server1
-	Server2SessionID string // Tracked session ID for server2
-	CreatedAt        time.Time
+	ClientSessionID string
+	Principal       string                    // Authenticated principal that created the session, empty when auth is off
+	Clients         map[string]*client.Client // Backend name -> dedicated client
+	SessionIDs      map[string]string         // Backend name -> tracked session ID, empty for a stateless backend
+	CreatedAt       time.Time
 }
 
 // SessionMapping holds the mapping between helper session and backend sessions
 type SessionMapping struct {
-	HelperSessionID  string
-	Server1SessionID string
-	Server2SessionID string
-	CreatedAt        time.Time
+	HelperSessionID string
+	Principal       string            // Authenticated principal that created the session, empty when auth is off
+	BackendSessions map[string]string // Backend name -> session ID
+	CreatedAt       time.Time
 }
 
 // MCPHelper represents the main MCP server that acts as both server and client
@@ -61,9 +211,42 @@ type MCPHelper struct {
 	// Server side
 	mcpServer *server.MCPServer
 
+	// Configured backends, from the config file or BACKENDS, in aggregation order
+	backends []BackendConfig
+
+	// Deadlines for helper-initiated operations
+	timeouts Timeouts
+
+	// Limits new sessions, each of which dials every backend (nil when disabled)
+	sessionLimiter *extProc.RateLimiter
+
+	// Caps initialize retries per backend across all sessions (nil when disabled)
+	retryBudget *extProc.RateLimiter
+
+	// Per-backend slots for in-flight session-creation initializes (nil when unlimited)
+	initSlots map[string]chan struct{}
+
+	// Tools only listed and callable for allowlisted principals
+	toolVisibility toolVisibilityRules
+
+	// ext-proc's tool call policies, applied to calls the helper forwards itself (nil applies none)
+	toolCallPolicies *extProc.Server
+
 	// Tool aggregation
-	aggregatedTools []mcp.Tool
-	toolsLock       sync.RWMutex
+	aggregatedTools  []mcp.Tool
+	degradedBackends map[string]string // backend name -> discovery error
+	unavailable      map[string]string // backend name -> initialize error, for backends skipped by degraded startup
+	toolBackends     map[string]string // aggregated tool name -> owning backend
+	registeredTools  map[string]string // tool name -> hash of the definition registered with mcpServer
+	resourceHashes   map[string]string // prefixed resource URI -> hash of the definition registered with mcpServer
+	promptHashes     map[string]string // prefixed prompt name -> hash of the definition registered with mcpServer
+
+	// Response timeouts tools ask for in their annotations, backend name -> aggregated tool name -> timeout
+	toolTimeouts map[string]map[string]time.Duration
+	toolsLock    sync.RWMutex
+
+	// Helper session IDs issued to clients that haven't been evicted or deleted
+	sessionIDs *sessionIDManager
 
 	// Session management - maps client session ID to backend client connections
 	clientConnections map[string]*ClientBackendConnections
@@ -73,46 +256,262 @@ type MCPHelper struct {
 	sessionMappings map[string]*SessionMapping
 	sessionLock     sync.RWMutex
 
-	// Startup clients (used only for initial tool discovery, then discarded)
-	startupServer1Client *client.Client
-	startupServer2Client *client.Client
+	// Concurrent aggregation triggers share one run rather than each querying every backend
+	aggregation singleFlight
+
+	// Concurrent initializes of one helper session share a single run, keyed by the session ID
+	sessionInits singleFlight
+
+	// Held by every writer of the tool set from fetching backend tools to swapping them in, so a slower
+	// run can't overwrite a newer one's result
+	toolSetLock sync.Mutex
+
+	// Backends whose tools/list_changed refresh is waiting out the debounce -> client sessions that heard it
+	toolsChanged     map[string]map[string]bool
+	toolsChangedLock sync.Mutex
+
+	// Lazy init state - discovery runs once, on the first client
+	discovered    bool
+	discoveryLock sync.Mutex
+
+	// Cached results of the background backend health checks, served by /readyz
+	health backendHealth
+
+	// Backends taken down for planned maintenance, whose requests get a friendly error instead of being routed
+	maintenance     map[string]bool
+	maintenanceLock sync.RWMutex
+
+	// Startup clients by backend name (used only for initial tool discovery, then discarded)
+	startupClients map[string]*client.Client
 }
 
 func main() {
 	var port = flag.String("port", "8080", "Port to listen on")
+	var maxConnections = flag.Int("max-connections", 1000, "Maximum concurrent HTTP connections (0 for unlimited)")
+	var metricsPort = flag.String("metrics-port", "9090", "Port serving Prometheus metrics on /metrics (empty to disable)")
+	var configPath = flag.String("config", "", "YAML or JSON file defining the backends, instead of BACKENDS and <NAME>_* env vars")
+	var selfTest = flag.Bool("selftest", false, "Route a synthetic tool call to each backend through ext-proc, report and exit")
+	flag.StringVar(&logFormat, "log-format", logFormat, "Log output format: text or json")
+	flag.StringVar(&logLevel, "log-level", logLevel, "Log level: debug, info, warn or error; debug adds request header dumps")
 	flag.Parse()
 
+	if err := setupLogging(logFormat, logLevel); err != nil {
+		log.Fatal(err)
+	}
+
 	log.Println("Starting MCP Helper...")
 
-	helper := NewMCPHelper()
+	// Fail fast on malformed backend config rather than deep inside transport initialization
+	var backends []BackendConfig
+	var err error
+	if *configPath != "" {
+		fileConfig, err := configFile.LoadConfig(*configPath)
+		if err != nil {
+			log.Fatal(err)
+		}
+		if backends, err = backendsFromConfig(fileConfig); err != nil {
+			log.Fatalf("Invalid backend configuration in %s: %v", *configPath, err)
+		}
+		log.Printf("Loaded %d backend(s) from %s", len(backends), *configPath)
+	} else if backends, err = loadBackends(); err != nil {
+		log.Fatalf("Invalid backend configuration: %v", err)
+	}
+	if err := checkDuplicateURLs(backends); err != nil {
+		log.Fatalf("Invalid backend configuration: %v", err)
+	}
+
+	timeouts := loadTimeouts()
+	helper := NewMCPHelper(backends, timeouts)
+
+	if err := validateInitParams(backends); err != nil {
+		log.Fatal(err)
+	}
+
+	// Prefixes and tool groups must be in place before aggregation names any tools
+	if err := registerBackendRoutes(backends); err != nil {
+		log.Fatal(err)
+	}
+
+	// Clients and the gateway carry the helper session on this header, everything else reads it from ext-proc
+	extProc.SetClientSessionHeader(clientSessionHeader)
 
-	// Initialize backend connections and aggregate tools
-	if err := helper.initializeBackends(); err != nil {
+	// Initialize backend connections and aggregate tools, or defer it to the first client
+	if lazyInit && !*selfTest {
+		log.Println("LAZY_INIT enabled, tool discovery will run when the first client connects")
+	} else if err := helper.initializeBackends(); err != nil {
 		log.Fatalf("Failed to initialize backends: %v", err)
 	}
 
+	helper.toolVisibility, err = parseToolVisibility(toolVisibilitySpec)
+	if err != nil {
+		log.Fatalf("Invalid TOOL_VISIBILITY: %v", err)
+	}
+	for toolName, principals := range helper.toolVisibility {
+		log.Printf("Tool visibility: %s restricted to %d principal(s)", toolName, len(principals))
+	}
+
+	// Authentication for the MCP endpoint (disabled unless AUTH_MODE is set)
+	authenticator, err := newAuthenticator()
+	if err != nil {
+		log.Fatalf("Invalid authentication config: %v", err)
+	}
+	if authenticator != nil {
+		log.Printf("🔒 MCP endpoint authentication enabled: %s", authMode)
+	}
+
+	// Backends /readyz depends on, so non-critical ones can be down without failing readiness
+	readinessRequired, err := parseReadinessRequired(readinessRequiredBackends, backends)
+	if err != nil {
+		log.Fatalf("Invalid READINESS_REQUIRED_BACKENDS: %v", err)
+	}
+
+	canaryRules, err := extProc.ParseCanaryRules(canaryRoutes)
+	if err != nil {
+		log.Fatalf("Invalid CANARY_ROUTES: %v", err)
+	}
+
+	statusRemaps, err := extProc.ParseStatusRemaps(statusRemapSpec)
+	if err != nil {
+		log.Fatalf("Invalid STATUS_REMAP: %v", err)
+	}
+	for from, remap := range statusRemaps {
+		log.Printf("Backend status remap: %d -> %d", from, remap.Status)
+	}
+	for _, rule := range canaryRules {
+		log.Printf("Canary routing: %d%% of %s -> %s (sticky: %v)", rule.Percent, rule.Match, rule.CanaryTarget, canarySticky)
+	}
+
+	responseTimeouts, err := extProc.ParseBackendResponseTimeouts(backendResponseTimeouts)
+	if err != nil {
+		log.Fatalf("Invalid BACKEND_RESPONSE_TIMEOUTS: %v", err)
+	}
+	// Timeouts from the config file apply unless BACKEND_RESPONSE_TIMEOUTS sets one for the backend
+	for _, backend := range backends {
+		if _, set := responseTimeouts[backend.Name]; !set && backend.ResponseTimeout > 0 {
+			responseTimeouts[backend.Name] = backend.ResponseTimeout
+		}
+	}
+	for target, timeout := range responseTimeouts {
+		log.Printf("Backend response timeout: %s -> %s", target, timeout)
+	}
+
+	var deadLetters *extProc.DeadLetterLog
+	if deadLetterLog != "" {
+		deadLetters, err = extProc.NewDeadLetterLog(deadLetterLog, strings.Split(redactFields, ","))
+		if err != nil {
+			log.Fatalf("Invalid DEAD_LETTER_LOG: %v", err)
+		}
+		log.Printf("Recording routing failures to %s", deadLetterLog)
+	}
+
+	extProcConfig := extProc.Config{
+		Phases:                  extProc.ParseProcessingPhases(extProcPhases),
+		RouteFailureMode:        extProc.ParseRouteFailureMode(routeFailureMode),
+		ValidateRequiredArgs:    validateRequiredArgs,
+		LenientJSONRPC:          lenientJSONRPC,
+		ResponseCacheTTL:        responseCacheTTL,
+		ResponseCacheSize:       responseCacheSize,
+		DebugLogging:            logLevel == "debug",
+		BackendDurationHeader:   backendDurationHeader,
+		OriginalToolNameHeader:  originalToolNameHeader,
+		RedactFields:            strings.Split(redactFields, ","),
+		BackendResponseTimeouts: responseTimeouts,
+		RateLimit:               rateLimit,
+		RateLimitBurst:          rateLimitBurst,
+		MaxRequestHeaders:       maxRequestHeaders,
+		MaxRequestHeaderBytes:   maxRequestHeaderBytes,
+		StatusRemaps:            statusRemaps,
+		DeadLetters:             deadLetters,
+		UnknownNotifications:    extProc.ParseUnknownNotificationPolicy(unknownNotificationPolicy),
+		OutputSchemaValidation:  extProc.ParseOutputSchemaValidation(outputSchemaValidation),
+		BackendContentType:      resolveBackendContentType(backendContentType),
+		Authenticator:           authenticator,
+		Canary: extProc.CanaryConfig{
+			Rules:  canaryRules,
+			Sticky: canarySticky,
+		},
+	}
+
+	// One processor serves every ext-proc stream, and the routing table is read from it
+	processor := extProc.NewServer(false, helper, extProcConfig)
+	helper.toolCallPolicies = processor
+
+	// Exercise the real routing pipeline against the live backends instead of serving traffic
+	if *selfTest {
+		os.Exit(helper.runSelfTest(processor, helper.mcpHandler()))
+	}
+
+	// Background loops run until shutdown: session eviction, so a long-running helper doesn't
+	// accumulate backend clients, the backend health checks behind /readyz and periodic tool refresh
+	backgroundCtx, stopBackground := context.WithCancel(context.Background())
+	defer stopBackground()
+	helper.StartSessionReaper(backgroundCtx, timeouts.SessionReap, timeouts.SessionTTL)
+	helper.StartHealthChecks(backgroundCtx, timeouts.HealthCheck)
+	helper.StartToolRefresh(backgroundCtx, timeouts.ToolRefresh)
+
+	if *metricsPort != "" {
+		helper.registerSessionMetrics()
+		go serveMetrics(*metricsPort)
+	}
+
+	config := buildEffectiveConfig(backends, *port, *maxConnections, timeouts, canaryRules, responseTimeouts, statusRemaps, readinessRequired)
+
 	// Setup signal handling for graceful shutdown
 	var gracefulStop = make(chan os.Signal, 1)
 	signal.Notify(gracefulStop, syscall.SIGTERM, syscall.SIGINT)
 
-	// Start the HTTP MCP Helper server in a goroutine
+	// Start the HTTP MCP Helper server in a goroutine, drained on shutdown
+	httpServer := &http.Server{}
 	go func() {
 		log.Printf("MCP Helper listening on port %s", *port)
 		log.Printf("MCP endpoint: http://localhost:%s", *port)
-		log.Printf("Backend servers: %s, %s", server1URL, server2URL)
+		log.Printf("Backend servers: %s", strings.Join(helper.backendNameList(), ", "))
 
-		streamableServer := server.NewStreamableHTTPServer(helper.mcpServer)
-
-		// Wrap the streamable server with logging middleware
-		loggingHandler := helper.loggingMiddleware(streamableServer)
+		// MCP requests are authenticated before anything else sees them
+		loggingHandler := authMiddleware(authenticator, helper.mcpHandler())
 
 		// Create a multiplexer to handle different routes
 		mux := http.NewServeMux()
 
+		// Effective configuration for operators, behind the same authentication as MCP
+		mux.Handle("/config", authMiddleware(authenticator, handleConfig(config)))
+
+		// Effective routing table, read from the ext-proc state that routes requests
+		mux.Handle("/routes", authMiddleware(authenticator, handleRoutes(processor)))
+
+		// Liveness and readiness probes, unauthenticated so load balancers and kubelets can reach them
+		mux.HandleFunc("/healthz", handleHealthz)
+		mux.Handle("/readyz", helper.handleReadyz(timeouts.HealthCheck, readinessRequired))
+
+		// Admin endpoints change routing state or expose session IDs, so they sit behind ADMIN_TOKEN
+		if adminToken != "" {
+			registerAdminRoutes(mux, helper, &bearerAuthenticator{token: adminToken})
+		} else {
+			log.Println("ADMIN_TOKEN not set, /admin endpoints are disabled")
+		}
+
 		// Handle all MCP requests
 		mux.Handle("/", loggingHandler)
 
-		if err := http.ListenAndServe(":"+*port, mux); err != nil {
+		httpLis, err := net.Listen("tcp", ":"+*port)
+		if err != nil {
+			log.Fatalf("HTTP Server failed to listen: %v", err)
+		}
+
+		// Cap concurrent connections so a connection flood can't exhaust file descriptors.
+		// Once the limit is hit, Accept blocks and new connections queue in the kernel backlog.
+		if *maxConnections > 0 {
+			log.Printf("Limiting HTTP listener to %d concurrent connections", *maxConnections)
+			httpLis = netutil.LimitListener(httpLis, *maxConnections)
+		}
+
+		// Header limits apply to every route, ahead of authentication and logging. The server's own
+		// header read limit is raised when needed so requests reach the middleware and get a clear 431.
+		httpServer.Handler = headerLimitMiddleware(maxRequestHeaders, maxRequestHeaderBytes, mux)
+		if maxRequestHeaderBytes > http.DefaultMaxHeaderBytes {
+			httpServer.MaxHeaderBytes = maxRequestHeaderBytes
+		}
+		if err := httpServer.Serve(httpLis); err != nil && !errors.Is(err, http.ErrServerClosed) {
 			log.Fatalf("HTTP Server error: %v", err)
 		}
 	}()
@@ -126,8 +525,27 @@ func main() {
 		log.Fatalf("failed to listen: %v", err)
 	}
 
-	s := grpc.NewServer()
-	extProcPb.RegisterExternalProcessorServer(s, extProc.NewServer(false, helper))
+	s := grpc.NewServer(
+		grpc.MaxConcurrentStreams(uint32(grpcMaxConcurrentStreams)),
+		grpc.KeepaliveEnforcementPolicy(keepalive.EnforcementPolicy{
+			MinTime:             grpcKeepaliveMinTime,
+			PermitWithoutStream: true,
+		}),
+		grpc.KeepaliveParams(keepalive.ServerParameters{
+			Time:    timeouts.Keepalive,
+			Timeout: timeouts.KeepaliveTimeout,
+		}),
+	)
+	log.Printf("ext-proc limits: max concurrent streams %d, keepalive min time %s",
+		grpcMaxConcurrentStreams, grpcKeepaliveMinTime)
+	extProcPb.RegisterExternalProcessorServer(s, processor)
+
+	// Standard gRPC health checking so Envoy and meshes can detect a healthy ext-proc.
+	// Backends are already initialized at this point, so report SERVING straight away.
+	healthServer := health.NewServer()
+	healthpb.RegisterHealthServer(s, healthServer)
+	healthServer.SetServingStatus("", healthpb.HealthCheckResponse_SERVING)
+	healthServer.SetServingStatus(extProcPb.ExternalProcessor_ServiceDesc.ServiceName, healthpb.HealthCheckResponse_SERVING)
 
 	// Register reflection service on gRPC server (for debugging only)
 	reflection.Register(s)
@@ -146,36 +564,81 @@ func main() {
 	log.Printf("Caught signal: %+v", sig)
 	log.Println("Shutting down servers...")
 
-	// Graceful shutdown
-	s.GracefulStop()
-	log.Println("Servers stopped")
+	// Graceful shutdown - report NOT_SERVING first so Envoy stops sending new streams
+	healthServer.Shutdown()
+
+	// Let in-flight MCP requests finish while ext-proc streams drain
+	httpDrained := make(chan struct{})
+	go func() {
+		defer close(httpDrained)
+		drainCtx, cancel := context.WithTimeout(context.Background(), timeouts.HTTPDrain)
+		defer cancel()
+		if err := httpServer.Shutdown(drainCtx); err != nil {
+			log.Printf("HTTP drain exceeded %s, closing remaining connections: %v", timeouts.HTTPDrain, err)
+			httpServer.Close()
+		}
+	}()
+
+	stopped := make(chan struct{})
+	go func() {
+		s.GracefulStop()
+		close(stopped)
+	}()
+
+	select {
+	case <-stopped:
+		log.Println("Servers stopped")
+	case <-time.After(timeouts.Shutdown):
+		log.Printf("Graceful shutdown exceeded %s, forcing stop", timeouts.Shutdown)
+		s.Stop()
+	}
+	<-httpDrained
+
+	stopBackground()
+	if err := helper.Close(); err != nil {
+		log.Printf("⚠️ Errors closing backend connections: %v", err)
+	}
+	if err := deadLetters.Close(); err != nil {
+		log.Printf("⚠️ Failed to close dead-letter log: %v", err)
+	}
+}
 
-	log.Println("Wait for 1 second to finish processing")
-	time.Sleep(1 * time.Second)
+// mcpHandler wraps the streamable MCP server with session header translation, compression, logging
+// and session rate limiting. Authentication is left to the caller.
+func (h *MCPHelper) mcpHandler() http.Handler {
+	streamableServer := server.NewStreamableHTTPServer(h.mcpServer, server.WithSessionIdManager(h.sessionIDs))
+	return h.loggingMiddleware(h.sessionRateLimitMiddleware(
+		compressionMiddleware(compressionMinBytes, sessionHeaderMiddleware(streamableServer))))
 }
 
 // loggingMiddleware adds comprehensive logging for all HTTP requests
 func (h *MCPHelper) loggingMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		// Log all headers for debugging
-		log.Printf("=== Helper REQUEST ===")
-		log.Printf("Method: %s, URL: %s", r.Method, r.URL.String())
-		log.Printf("Headers:")
-		for name, values := range r.Header {
-			for _, value := range values {
-				log.Printf("  %s: %s", name, value)
+		// Dump all headers only at debug level, they flood production logs
+		if slog.Default().Enabled(r.Context(), slog.LevelDebug) {
+			log.Printf("=== Helper REQUEST ===")
+			log.Printf("Method: %s, URL: %s", r.Method, r.URL.String())
+			log.Printf("Headers:")
+			for name, values := range r.Header {
+				for _, value := range values {
+					log.Printf("  %s: %s", name, loggedHeaderValue(name, value))
+				}
 			}
+			log.Printf("======================")
 		}
 
 		// Specifically log session header
-		sessionID := r.Header.Get("mcp-session-id")
+		sessionID := r.Header.Get(extProc.ClientSessionHeader())
 		if sessionID != "" {
-			log.Printf("🔑 MCP-SESSION-ID: %s", sessionID)
+			log.Printf("🔑 %s: %s", strings.ToUpper(extProc.ClientSessionHeader()), sessionID)
 		} else {
-			log.Printf("❌ No mcp-session-id header found")
+			log.Printf("❌ No %s header found", extProc.ClientSessionHeader())
 		}
 
-		log.Printf("======================")
+		principal := principalFromContext(r.Context())
+		if principal != "" {
+			log.Printf("👤 Principal: %s", principal)
+		}
 
 		// Check if this is an initialize request
 		if r.Method == "POST" && (r.URL.Path == "/" || r.URL.Path == "/mcp") {
@@ -183,6 +646,7 @@ func (h *MCPHelper) loggingMiddleware(next http.Handler) http.Handler {
 			wrappedWriter := &sessionCapturingWriter{
 				ResponseWriter: w,
 				helper:         h,
+				principal:      principal,
 			}
 			next.ServeHTTP(wrappedWriter, r)
 		} else {
@@ -191,10 +655,88 @@ func (h *MCPHelper) loggingMiddleware(next http.Handler) http.Handler {
 	})
 }
 
+// resolveBackendContentType maps BACKEND_CONTENT_TYPE to the ext-proc setting, where empty preserves the client's
+func resolveBackendContentType(contentType string) string {
+	if strings.EqualFold(contentType, "preserve") {
+		return ""
+	}
+	return contentType
+}
+
+// newSessionLimiter creates the session creation rate limiter, or nil when disabled
+func newSessionLimiter() *extProc.RateLimiter {
+	if sessionRateLimit <= 0 {
+		return nil
+	}
+	log.Printf("Limiting session creation to %g/s (burst %d) per client", sessionRateLimit, sessionRateLimitBurst)
+	return extProc.NewRateLimiter(sessionRateLimit, sessionRateLimitBurst)
+}
+
+// sessionRateLimitMiddleware rejects initialize requests over the session creation rate
+// before any backend is dialed
+func (h *MCPHelper) sessionRateLimitMiddleware(next http.Handler) http.Handler {
+	if h.sessionLimiter == nil {
+		return next
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.Body == nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			http.Error(w, "Failed to read request body", http.StatusBadRequest)
+			return
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
+
+		var message struct {
+			Method string `json:"method"`
+		}
+		if json.Unmarshal(body, &message) != nil || message.Method != string(mcp.MethodInitialize) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		// Key on the principal when authenticated, otherwise on the client address
+		key := "principal:" + principalFromContext(r.Context())
+		if key == "principal:" {
+			key = "ip:" + clientIP(r)
+		}
+		if !h.sessionLimiter.Allow(key) {
+			log.Printf("🚦 Session creation rate limit exceeded for %s", key)
+			w.Header().Set("Retry-After", "1")
+			http.Error(w, "Too many new sessions, retry later", http.StatusTooManyRequests)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// clientIP returns the originating client address: the X-Forwarded-For hop appended by the outermost of the
+// trusted proxies, or the connection's address when there are none. Clients can prepend any hops they like,
+// so the first hop is never used unless a trusted proxy appended it.
+func clientIP(r *http.Request) string {
+	if forwarded := r.Header.Values("X-Forwarded-For"); trustedProxyHops > 0 && len(forwarded) > 0 {
+		hops := strings.Split(strings.Join(forwarded, ","), ",")
+		return strings.TrimSpace(hops[max(0, len(hops)-trustedProxyHops)])
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
 // sessionCapturingWriter wraps http.ResponseWriter to capture session IDs from initialize responses
 type sessionCapturingWriter struct {
 	http.ResponseWriter
-	helper *MCPHelper
+	helper    *MCPHelper
+	principal string // authenticated principal of the request, recorded on new sessions
+	captured  bool   // set once the session has been captured so multi-chunk writes don't re-initialize
 }
 
 func (w *sessionCapturingWriter) Header() http.Header {
@@ -203,14 +745,16 @@ func (w *sessionCapturingWriter) Header() http.Header {
 
 func (w *sessionCapturingWriter) Write(data []byte) (int, error) {
 	// Check if a new session ID was set in the response headers
-	if sessionID := w.Header().Get("mcp-session-id"); sessionID != "" {
+	if sessionID := w.Header().Get(extProc.ClientSessionHeader()); sessionID != "" && !w.captured {
+		w.captured = true
+
 		// This is likely a response to an initialize request
 		go func() {
 			// Create session mapping asynchronously
-			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			ctx, cancel := context.WithTimeout(context.Background(), w.helper.sessionInitTimeout(w.helper.timeouts.Init))
 			defer cancel()
 
-			if err := w.helper.handleInitialization(ctx, sessionID); err != nil {
+			if err := w.helper.handleInitialization(ctx, sessionID, w.principal); err != nil {
 				log.Printf("❌ Failed to create session mapping for %s: %v", sessionID, err)
 			}
 		}()
@@ -219,23 +763,82 @@ func (w *sessionCapturingWriter) Write(data []byte) (int, error) {
 	return w.ResponseWriter.Write(data)
 }
 
+// Flush passes flushes through so SSE responses to POSTs stream instead of buffering
+func (w *sessionCapturingWriter) Flush() {
+	if flusher, ok := w.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
 func (w *sessionCapturingWriter) WriteHeader(statusCode int) {
 	w.ResponseWriter.WriteHeader(statusCode)
 }
 
-// NewMCPHelper creates a new MCP Helper instance
-func NewMCPHelper() *MCPHelper {
+// NewMCPHelper creates a new MCP Helper instance for the parsed backend configuration
+func NewMCPHelper(backends []BackendConfig, timeouts Timeouts) *MCPHelper {
 	helper := &MCPHelper{
+		backends:          backends,
+		timeouts:          timeouts,
+		sessionLimiter:    newSessionLimiter(),
+		retryBudget:       newRetryBudget(),
+		sessionIDs:        newSessionIDManager(),
 		aggregatedTools:   make([]mcp.Tool, 0),
+		degradedBackends:  make(map[string]string),
+		unavailable:       make(map[string]string),
+		toolBackends:      make(map[string]string),
+		toolTimeouts:      make(map[string]map[string]time.Duration),
+		toolsChanged:      make(map[string]map[string]bool),
+		registeredTools:   make(map[string]string),
+		resourceHashes:    make(map[string]string),
+		promptHashes:      make(map[string]string),
+		maintenance:       make(map[string]bool),
 		clientConnections: make(map[string]*ClientBackendConnections),
 		sessionMappings:   make(map[string]*SessionMapping),
 	}
+	helper.initSlots = newInitSlots(helper.backendNameList()...)
+	helper.sessionIDs.onTerminate = helper.endSession
+
+	// Answer MCP ping locally - liveness checks never touch the backends
+	hooks := &server.Hooks{}
+	hooks.AddBeforePing(func(ctx context.Context, id any, message *mcp.PingRequest) {
+		log.Printf("🏓 Ping received (id: %v), answering locally", id)
+	})
+
+	// Lazy init discovers tools on the first client, before it can list them
+	if lazyInit {
+		hooks.AddBeforeInitialize(func(ctx context.Context, id any, message *mcp.InitializeRequest) {
+			helper.ensureToolsDiscovered()
+		})
+		hooks.AddBeforeListTools(func(ctx context.Context, id any, message *mcp.ListToolsRequest) {
+			helper.ensureToolsDiscovered()
+		})
+	}
+
+	// Relay logging/setLevel to the client's backend sessions once the helper has accepted it
+	hooks.AddAfterSetLevel(func(ctx context.Context, id any, message *mcp.SetLevelRequest, result *mcp.EmptyResult) {
+		helper.relaySetLevel(ctx, message)
+	})
+
+	// Flag a partial tool set on tools/list so clients can tell users some backends are missing
+	hooks.AddAfterListTools(func(ctx context.Context, id any, message *mcp.ListToolsRequest, result *mcp.ListToolsResult) {
+		if degraded := helper.getDegradedBackends(); len(degraded) > 0 {
+			if result.Meta == nil {
+				result.Meta = make(map[string]any)
+			}
+			result.Meta["mcp-helper/degradedBackends"] = degraded
+		}
+	})
 
-	// Create MCP server with tool capabilities
+	// Create MCP server with tool, resource and prompt capabilities
 	helper.mcpServer = server.NewMCPServer(
 		"MCP Helper",
 		"1.0.0",
 		server.WithToolCapabilities(true),
+		server.WithResourceCapabilities(false, true),
+		server.WithPromptCapabilities(true),
+		server.WithLogging(),
+		server.WithHooks(hooks),
+		server.WithToolFilter(helper.filterVisibleTools),
 	)
 
 	// Setup helper handlers
@@ -250,68 +853,244 @@ func (h *MCPHelper) setupHandlers() {
 	h.mcpServer.AddTool(mcp.NewTool("helper_info",
 		mcp.WithDescription("Get information about the MCP Helper"),
 	), h.handleHelperInfo)
+
+	// helper status tool - machine-readable backend availability
+	h.mcpServer.AddTool(mcp.NewTool("helper_status",
+		mcp.WithDescription("Report which backends are unavailable, so a partial tool list can be explained"),
+		mcp.WithReadOnlyHintAnnotation(true),
+	), h.handleHelperStatus)
+
+	// tool ownership - which backend serves each aggregated tool, without parsing names
+	h.mcpServer.AddTool(mcp.NewTool("helper_tool_backend",
+		mcp.WithDescription("Map aggregated tool names to the backend that owns them and the response timeout calls get, for one tool or all of them"),
+		mcp.WithReadOnlyHintAnnotation(true),
+		mcp.WithString("tool",
+			mcp.Description("Aggregated tool name to look up; omit to list every tool"),
+		),
+	), h.handleToolBackend)
+
+	// explicit routing - call a backend tool by backend and tool name, bypassing prefix conventions
+	h.mcpServer.AddTool(mcp.NewTool("helper_call",
+		mcp.WithDescription("Call a tool on a specific backend by its backend tool name, without the aggregated prefix"),
+		mcp.WithString("backend",
+			mcp.Required(),
+			mcp.Description("Backend serving the tool"),
+			mcp.Enum(h.backendNameList()...),
+		),
+		mcp.WithString("tool",
+			mcp.Required(),
+			mcp.Description("Tool name as the backend lists it"),
+		),
+		mcp.WithObject("arguments",
+			mcp.Description("Arguments for the backend tool"),
+		),
+	), h.handleHelperCall)
+}
+
+// relaySetLevel forwards a logging/setLevel request to the backend sessions of the requesting client
+func (h *MCPHelper) relaySetLevel(ctx context.Context, request *mcp.SetLevelRequest) {
+	session := server.ClientSessionFromContext(ctx)
+	if session == nil {
+		log.Printf("❌ logging/setLevel received without a client session, not relaying")
+		return
+	}
+	helperSessionID := session.SessionID()
+
+	h.connectionsLock.RLock()
+	connections, exists := h.clientConnections[helperSessionID]
+	h.connectionsLock.RUnlock()
+	if !exists {
+		log.Printf("❌ No backend connections for session %s, not relaying logging/setLevel", helperSessionID)
+		return
+	}
+
+	relayCtx, cancel := context.WithTimeout(ctx, h.timeouts.Init)
+	defer cancel()
+
+	for _, backend := range h.backends {
+		backendClient := connections.Clients[backend.Name]
+		if backendClient == nil {
+			continue
+		}
+		if err := backendClient.SetLevel(relayCtx, *request); err != nil {
+			log.Printf("⚠️ Failed to relay logging/setLevel %s to %s for session %s: %v",
+				request.Params.Level, backend.Name, helperSessionID, err)
+			continue
+		}
+		log.Printf("✅ Relayed logging/setLevel %s to %s for session %s", request.Params.Level, backend.Name, helperSessionID)
+	}
+}
+
+// handleInitialization creates backend sessions when a client initializes. Initializes racing on the same
+// helper session ID share one run, so neither can store a mapping to backend sessions the other closed.
+func (h *MCPHelper) handleInitialization(ctx context.Context, helperSessionID, principal string) error {
+	shared, err := h.sessionInits.do(helperSessionID, func() error {
+		return h.initializeSession(ctx, helperSessionID, principal)
+	})
+	if shared {
+		log.Printf("♻️ Joined initialization of helper session %s already in progress", helperSessionID)
+	}
+	return err
 }
 
-// handleInitialization creates backend sessions when a client initializes
-func (h *MCPHelper) handleInitialization(ctx context.Context, helperSessionID string) error {
+// initializeSession creates a helper session's backend connections and stores them with its mapping
+func (h *MCPHelper) initializeSession(ctx context.Context, helperSessionID, principal string) error {
+	// A reconnecting client may re-initialize with the same session ID - reuse its backend sessions
+	h.sessionLock.RLock()
+	_, mapped := h.sessionMappings[helperSessionID]
+	h.sessionLock.RUnlock()
+
+	h.connectionsLock.RLock()
+	_, connected := h.clientConnections[helperSessionID]
+	h.connectionsLock.RUnlock()
+
+	if mapped && connected {
+		log.Printf("♻️ Helper session %s already initialized, reusing backend sessions", helperSessionID)
+		return nil
+	}
+
 	log.Printf("🆕 Creating backend sessions for helper session: %s", helperSessionID)
+	start := time.Now()
 
 	// Create backend connections
 	// TODO: Make this reactive, when a tool call is made, create the backend connection & session mapping if they don't exist
-	connections, err := h.createBackendConnectionsForSession(ctx, helperSessionID)
+	connections, err := h.createBackendConnectionsForSession(ctx, helperSessionID, principal)
 	if err != nil {
 		return fmt.Errorf("failed to create backend connections: %w", err)
 	}
 
-	// Store session mapping
 	mapping := &SessionMapping{
-		HelperSessionID:  helperSessionID,
-		Server1SessionID: connections.Server1SessionID,
-		Server2SessionID: connections.Server2SessionID,
-		CreatedAt:        time.Now(),
+		HelperSessionID: helperSessionID,
+		Principal:       principal,
+		BackendSessions: connections.SessionIDs,
+		CreatedAt:       time.Now(),
 	}
 
+	// The mapping and the connections it names are stored together, so routing never sees one without
+	// the other. Session creation is the only place both locks are held, always session lock first.
 	h.sessionLock.Lock()
+	h.connectionsLock.Lock()
+	previous := h.clientConnections[helperSessionID]
+	h.clientConnections[helperSessionID] = connections
 	h.sessionMappings[helperSessionID] = mapping
+	h.connectionsLock.Unlock()
 	h.sessionLock.Unlock()
 
-	log.Printf("✅ session mapping created: %s -> server1:%s, server2:%s",
-		helperSessionID, connections.Server1SessionID, connections.Server2SessionID)
+	// A half-initialized earlier attempt, with connections but no mapping, is torn down
+	if previous != nil {
+		log.Printf("♻️ Replacing existing backend connections for session %s", helperSessionID)
+		h.closeBackendConnections(previous)
+	}
+
+	slog.Info("session created",
+		"session_id", helperSessionID,
+		"principal", principal,
+		"backend_sessions", connections.SessionIDs,
+		"latency_ms", time.Since(start).Milliseconds())
+
+	// Prime backends with expensive lazy setup in the background, the mapping is already usable
+	go h.warmUpSession(connections)
 
 	return nil
 }
 
 // createBackendConnectionsForSession creates and initializes backend connections
-func (h *MCPHelper) createBackendConnectionsForSession(ctx context.Context, helperSessionID string) (*ClientBackendConnections, error) {
+func (h *MCPHelper) createBackendConnectionsForSession(ctx context.Context, helperSessionID, principal string) (*ClientBackendConnections, error) {
 	log.Printf("🔗 Creating backend connections for session: %s", helperSessionID)
 
 	connections := &ClientBackendConnections{
 		ClientSessionID: helperSessionID,
+		Principal:       principal,
+		Clients:         make(map[string]*client.Client, len(h.backends)),
+		SessionIDs:      make(map[string]string, len(h.backends)),
 		CreatedAt:       time.Now(),
 	}
 
-	// Create and initialize server1 connection
-	client1, sessionID1, err := h.createClientBackendConnection(ctx, connections.ClientSessionID, "server1", server1URL)
-	if err != nil {
-		return nil, fmt.Errorf("failed to create server1 connection: %w", err)
+	// Create and initialize a connection to each backend
+	for _, backend := range h.backends {
+		if _, unavailable := h.BackendUnavailable(backend.Name); unavailable {
+			log.Printf("⏭️ Skipping unavailable backend %s for session %s", backend.Name, helperSessionID)
+			continue
+		}
+		start := time.Now()
+		backendClient, sessionID, err := h.createClientBackendConnection(ctx, connections.ClientSessionID, backend)
+		observeBackendInit(backend.Name, start)
+		if err != nil {
+			slog.Warn("backend connection failed",
+				"session_id", helperSessionID,
+				"backend", backend.Name,
+				"optional", backend.Optional,
+				"latency_ms", time.Since(start).Milliseconds(),
+				"error", err)
+		}
+		if err != nil && backend.Optional {
+			log.Printf("⚠️ Optional backend %s is unavailable for session %s, continuing without it", backend.Name, helperSessionID)
+			continue
+		}
+		if err != nil {
+			h.closeBackendConnections(connections)
+			return nil, fmt.Errorf("failed to create %s connection: %w", backend.Name, err)
+		}
+		connections.Clients[backend.Name] = backendClient
+		connections.SessionIDs[backend.Name] = sessionID
 	}
-	connections.Server1Client = client1
-	connections.Server1SessionID = sessionID1
 
-	// Create and initialize server2 connection
-	client2, sessionID2, err := h.createClientBackendConnection(ctx, connections.ClientSessionID, "server2", server2URL)
-	if err != nil {
-		return nil, fmt.Errorf("failed to create server2 connection: %w", err)
+	return connections, nil
+}
+
+// closeBackendConnections closes the backend clients held by a client session
+func (h *MCPHelper) closeBackendConnections(connections *ClientBackendConnections) error {
+	var errs []error
+	for name, backendClient := range connections.Clients {
+		if backendClient == nil {
+			continue
+		}
+		if err := backendClient.Close(); err != nil {
+			log.Printf("⚠️ Failed to close %s connection for session %s: %v", name, connections.ClientSessionID, err)
+			errs = append(errs, fmt.Errorf("%s connection for session %s: %w", name, connections.ClientSessionID, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// closeStartupClients closes the discovery clients, if any
+func (h *MCPHelper) closeStartupClients() error {
+	var errs []error
+	for name, startupClient := range h.startupClients {
+		if err := startupClient.Close(); err != nil {
+			errs = append(errs, fmt.Errorf("startup %s connection: %w", name, err))
+		}
+	}
+	h.startupClients = nil
+	return errors.Join(errs...)
+}
+
+// Close tears down every backend client the helper holds, startup and per-session,
+// and clears the session state. It returns the errors from closing any clients.
+func (h *MCPHelper) Close() error {
+	var errs []error
+
+	if err := h.closeStartupClients(); err != nil {
+		errs = append(errs, err)
 	}
-	connections.Server2Client = client2
-	connections.Server2SessionID = sessionID2
 
-	// Store the connections for later use
 	h.connectionsLock.Lock()
-	h.clientConnections[helperSessionID] = connections
+	connections := h.clientConnections
+	h.clientConnections = make(map[string]*ClientBackendConnections)
 	h.connectionsLock.Unlock()
 
-	return connections, nil
+	for _, conns := range connections {
+		if err := h.closeBackendConnections(conns); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	h.sessionLock.Lock()
+	h.sessionMappings = make(map[string]*SessionMapping)
+	h.sessionLock.Unlock()
+
+	log.Printf("Closed backend connections for %d sessions", len(connections))
+	return errors.Join(errs...)
 }
 
 // GetSessionMapping returns the session mapping for a helper session ID (implements SessionMapper interface)
@@ -326,35 +1105,85 @@ func (g *MCPHelper) GetSessionMapping(helperSessionID string) (*extProc.SessionM
 
 	// Convert to extProc.SessionMapping
 	return &extProc.SessionMapping{
-		HelperSessionID:  mapping.HelperSessionID,
-		Server1SessionID: mapping.Server1SessionID,
-		Server2SessionID: mapping.Server2SessionID,
+		HelperSessionID: mapping.HelperSessionID,
+		Principal:       mapping.Principal,
+		BackendSessions: maps.Clone(mapping.BackendSessions),
 	}, true
 }
 
-// DumpAllSessions logs all current session mappings for debugging
-func (g *MCPHelper) DumpAllSessions() {
+// GetToolRequiredArguments returns the required arguments declared by an aggregated tool (implements ToolSchemaLookup interface)
+func (g *MCPHelper) GetToolRequiredArguments(toolName string) ([]string, bool) {
+	g.toolsLock.RLock()
+	defer g.toolsLock.RUnlock()
+
+	for _, tool := range g.aggregatedTools {
+		if tool.Name == toolName {
+			required := make([]string, len(tool.InputSchema.Required))
+			copy(required, tool.InputSchema.Required)
+			return required, true
+		}
+	}
+	return nil, false
+}
+
+// IsToolReadOnly reports whether an aggregated tool is annotated read-only (implements ToolSchemaLookup interface)
+func (g *MCPHelper) IsToolReadOnly(toolName string) bool {
+	g.toolsLock.RLock()
+	defer g.toolsLock.RUnlock()
+
+	for _, tool := range g.aggregatedTools {
+		if tool.Name == toolName {
+			return tool.Annotations.ReadOnlyHint != nil && *tool.Annotations.ReadOnlyHint
+		}
+	}
+	return false
+}
+
+// DumpAllSessions returns a snapshot of every session mapping, sorted by helper session ID
+// (implements SessionMapper interface)
+func (g *MCPHelper) DumpAllSessions() []extProc.SessionMapping {
 	g.sessionLock.RLock()
 	defer g.sessionLock.RUnlock()
 
-	log.Printf("🔍 [HELPER] Session Store Dump - Total sessions: %d", len(g.sessionMappings))
-	if len(g.sessionMappings) == 0 {
-		log.Printf("🔍 [HELPER] Session store is empty")
-		return
+	sessions := make([]extProc.SessionMapping, 0, len(g.sessionMappings))
+	for _, mapping := range g.sessionMappings {
+		sessions = append(sessions, extProc.SessionMapping{
+			HelperSessionID: mapping.HelperSessionID,
+			Principal:       mapping.Principal,
+			BackendSessions: maps.Clone(mapping.BackendSessions),
+		})
 	}
+	sort.Slice(sessions, func(i, j int) bool {
+		return sessions[i].HelperSessionID < sessions[j].HelperSessionID
+	})
+	return sessions
+}
 
-	for helperID, mapping := range g.sessionMappings {
-		log.Printf("🔍 [HELPER] Session: %s", helperID)
-		log.Printf("  └── Helper:  %s", mapping.HelperSessionID)
-		log.Printf("  └── Server1: %s", mapping.Server1SessionID)
-		log.Printf("  └── Server2: %s", mapping.Server2SessionID)
+// ensureToolsDiscovered runs tool discovery once for lazy init. Concurrent first clients wait
+// for the same run; a failed run is retried by the next client.
+func (g *MCPHelper) ensureToolsDiscovered() {
+	g.discoveryLock.Lock()
+	defer g.discoveryLock.Unlock()
+
+	if g.discovered {
+		return
+	}
+	if err := g.initializeBackends(); err != nil {
+		log.Printf("❌ Lazy tool discovery failed, retrying on the next client: %v", err)
+		return
 	}
+	g.discovered = true
 }
 
 // initializeBackends connects to backend servers for initial tool discovery only
 func (g *MCPHelper) initializeBackends() error {
 	log.Println("Initializing backend server connections for tool discovery...")
 
+	// A retried lazy discovery must not leak clients from the failed attempt
+	if err := g.closeStartupClients(); err != nil {
+		log.Printf("⚠️ Failed to close previous startup clients: %v", err)
+	}
+
 	// Initialize startup clients (these will be discarded after tool discovery)
 	if err := g.initializeStartupClients(); err != nil {
 		return fmt.Errorf("failed to initialize startup clients: %w", err)
@@ -365,60 +1194,53 @@ func (g *MCPHelper) initializeBackends() error {
 		return fmt.Errorf("failed to aggregate tools: %w", err)
 	}
 
+	// Resources and prompts are optional for backends, so their discovery never fails initialization
+	g.aggregateResources()
+	g.aggregatePrompts()
+
 	log.Printf("Successfully initialized. Aggregated %d tools from backend servers.", len(g.aggregatedTools))
 	log.Println("Startup clients will be discarded - per-client sessions will be created on demand.")
 	return nil
 }
 
-// initializeStartupClients creates temporary clients for tool discovery
-// Hardcoded for now, will be replaced with a more dynamic approach
+// initializeStartupClients creates temporary clients for tool discovery, one per configured backend
 func (g *MCPHelper) initializeStartupClients() error {
-	// Initialize startup server1 client
-	log.Printf("Creating startup connection to server1 at %s...", server1URL)
-	httpTransport1, err := transport.NewStreamableHTTP(server1URL)
-	if err != nil {
-		return fmt.Errorf("failed to create HTTP transport for server1: %w", err)
-	}
-	g.startupServer1Client = client.NewClient(httpTransport1)
-
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
-	defer cancel()
-
-	initRequest1 := mcp.InitializeRequest{}
-	initRequest1.Params.ProtocolVersion = mcp.LATEST_PROTOCOL_VERSION
-	initRequest1.Params.ClientInfo = mcp.Implementation{
-		Name:    "MCP Helper (Startup)",
-		Version: "1.0.0",
-	}
-	initRequest1.Params.Capabilities = mcp.ClientCapabilities{}
-
-	serverInfo1, err := g.startupServer1Client.Initialize(ctx, initRequest1)
-	if err != nil {
-		return fmt.Errorf("failed to initialize startup server1: %w", err)
-	}
-	log.Printf("Startup connection to server1: %s (version %s)", serverInfo1.ServerInfo.Name, serverInfo1.ServerInfo.Version)
-
-	// Initialize startup server2 client
-	log.Printf("Creating startup connection to server2 at %s...", server2URL)
-	httpTransport2, err := transport.NewStreamableHTTP(server2URL)
-	if err != nil {
-		return fmt.Errorf("failed to create HTTP transport for server2: %w", err)
-	}
-	g.startupServer2Client = client.NewClient(httpTransport2)
+	g.startupClients = make(map[string]*client.Client, len(g.backends))
+	for _, backend := range g.backends {
+		log.Printf("Creating startup connection to %s at %s...", backend.Name, redactURL(backend.URL))
+		httpTransport, err := transport.NewStreamableHTTP(backend.URL)
+		if err != nil {
+			return fmt.Errorf("failed to create HTTP transport for %s: %w", backend.Name, err)
+		}
+		startupClient := client.NewClient(httpTransport)
 
-	initRequest2 := mcp.InitializeRequest{}
-	initRequest2.Params.ProtocolVersion = mcp.LATEST_PROTOCOL_VERSION
-	initRequest2.Params.ClientInfo = mcp.Implementation{
-		Name:    "MCP Helper (Startup)",
-		Version: "1.0.0",
-	}
-	initRequest2.Params.Capabilities = mcp.ClientCapabilities{}
+		initRequest := mcp.InitializeRequest{}
+		initRequest.Params.ProtocolVersion = mcp.LATEST_PROTOCOL_VERSION
+		initRequest.Params.ClientInfo = mcp.Implementation{
+			Name:    "MCP Helper (Startup)",
+			Version: "1.0.0",
+		}
+		initRequest.Params.Capabilities = mcp.ClientCapabilities{}
+		applyInitParams(backend, &initRequest.Params)
 
-	serverInfo2, err := g.startupServer2Client.Initialize(ctx, initRequest2)
-	if err != nil {
-		return fmt.Errorf("failed to initialize startup server2: %w", err)
+		ctx, cancel := context.WithTimeout(context.Background(), backend.initTimeout(g.timeouts.Discovery))
+		serverInfo, err := startupClient.Initialize(ctx, initRequest)
+		cancel()
+		if err != nil {
+			startupClient.Close()
+			if backend.Optional {
+				log.Printf("⚠️ Optional backend %s is unavailable, continuing without it: %v", backend.Name, err)
+				continue
+			}
+			if degradedStartup {
+				g.markBackendUnavailable(backend.Name, err)
+				continue
+			}
+			return fmt.Errorf("failed to initialize startup %s: %w", backend.Name, err)
+		}
+		g.startupClients[backend.Name] = startupClient
+		log.Printf("Startup connection to %s: %s (version %s)", backend.Name, serverInfo.ServerInfo.Name, serverInfo.ServerInfo.Version)
 	}
-	log.Printf("Startup connection to server2: %s (version %s)", serverInfo2.ServerInfo.Name, serverInfo2.ServerInfo.Version)
 
 	return nil
 }
@@ -431,83 +1253,361 @@ type serverConfig struct {
 	client *client.Client
 }
 
+// aggregateTools aggregates tools from all backends, joining any aggregation already in progress
 func (g *MCPHelper) aggregateTools() error {
+	shared, err := g.aggregation.do("aggregate", g.runAggregation)
+	if shared {
+		log.Println("Joined tool aggregation already in progress")
+	}
+	return err
+}
+
+// discoverEach runs discover for each named backend in parallel, at most discoveryConcurrency at a time.
+// ctx carries the discovery budget for the whole round and each call also gets its own discovery timeout;
+// a backend still waiting for a slot when the budget runs out fails without being queried.
+func (g *MCPHelper) discoverEach(ctx context.Context, names []string, discover func(ctx context.Context, i int) error) []error {
+	errs := make([]error, len(names))
+	concurrency := discoveryConcurrency
+	if concurrency <= 0 {
+		concurrency = len(names)
+	}
+	slots := make(chan struct{}, concurrency)
+	var wg sync.WaitGroup
+	for i, name := range names {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			select {
+			case slots <- struct{}{}:
+				defer func() { <-slots }()
+			case <-ctx.Done():
+				errs[i] = fmt.Errorf("discovery budget exhausted before querying %s: %w", name, ctx.Err())
+				return
+			}
+
+			discoveryCtx, cancel := context.WithTimeout(ctx, g.timeouts.Discovery)
+			defer cancel()
+			errs[i] = discover(discoveryCtx, i)
+		}()
+	}
+	wg.Wait()
+	return errs
+}
+
+// runAggregation lists tools from every backend and replaces the aggregated set
+func (g *MCPHelper) runAggregation() error {
+	g.toolSetLock.Lock()
+	defer g.toolSetLock.Unlock()
 	log.Println("Aggregating tools from backend servers using startup clients...")
 
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	// The budget bounds the whole aggregation, each backend also gets its own discovery timeout
+	budgetCtx, cancel := context.WithTimeout(context.Background(), g.timeouts.DiscoveryBudget)
 	defer cancel()
 
 	// Define server configurations
-	servers := []serverConfig{
-		{name: "server1", prefix: "server1-", client: g.startupServer1Client},
-		{name: "server2", prefix: "server2-", client: g.startupServer2Client},
+	servers := make([]serverConfig, 0, len(g.backends))
+	for _, backend := range g.backends {
+		servers = append(servers, serverConfig{name: backend.Name, prefix: extProc.ToolPrefix(backend.Name), client: g.startupClients[backend.Name]})
 	}
 
-	var allTools []mcp.Tool
+	// Query backends in parallel, at most discoveryConcurrency at a time
+	results := make([]*mcp.ListToolsResult, len(servers))
+	hints := make([]map[string]time.Duration, len(servers))
+	errs := g.discoverEach(budgetCtx, g.backendNameList(), func(ctx context.Context, i int) error {
+		if servers[i].client == nil {
+			return fmt.Errorf("%s is not connected", servers[i].name)
+		}
+		var err error
+		results[i], hints[i], err = listBackendTools(ctx, servers[i].client)
+		return err
+	})
 
-	// Process each server
-	for _, server := range servers {
-		tools, err := server.client.ListTools(ctx, mcp.ListToolsRequest{})
-		if err != nil {
-			return fmt.Errorf("failed to list tools from %s: %w", server.name, err)
+	var allTools []mcp.Tool
+	degraded := make(map[string]string)
+	owners := make(map[string]string)
+	timeouts := make(map[string]map[string]time.Duration)
+
+	// Process each server's result in configuration order
+	for i, server := range servers {
+		if errs[i] != nil {
+			// One failing backend shouldn't take down discovery for the rest
+			log.Printf("⚠️ Failed to list tools from %s, marking degraded: %v", server.name, errs[i])
+			degraded[server.name] = errs[i].Error()
+			continue
 		}
 
-		// Prefix tools from this server
-		for _, tool := range tools.Tools {
-			prefixedTool := tool
+		// Prefix tools from this server. Overlapping prefixes can give two backends' tools the same
+		// aggregated name; the first backend keeps it so the routing table stays unambiguous.
+		for _, tool := range results[i].Tools {
+			prefixedTool := limitToolSchema(tool, maxToolSchemaBytes)
 			prefixedTool.Name = server.prefix + tool.Name
+			if owner, taken := owners[prefixedTool.Name]; taken {
+				log.Printf("⚠️ %s lists %s, which %s already serves, skipping it", server.name, prefixedTool.Name, owner)
+				continue
+			}
 			allTools = append(allTools, prefixedTool)
+			owners[prefixedTool.Name] = server.name
+			if timeout, ok := hints[i][tool.Name]; ok {
+				if timeouts[server.name] == nil {
+					timeouts[server.name] = make(map[string]time.Duration)
+				}
+				timeouts[server.name][prefixedTool.Name] = timeout
+			}
 		}
-		log.Printf("%s contributed %d tools", server.name, len(tools.Tools))
+		log.Printf("%s contributed %d tools", server.name, len(results[i].Tools))
 	}
 
 	// Store aggregated tools
 	g.toolsLock.Lock()
 	g.aggregatedTools = allTools
+	g.degradedBackends = degraded
+	g.toolBackends = owners
+	g.toolTimeouts = timeouts
 	g.toolsLock.Unlock()
 
+	if len(degraded) > 0 {
+		log.Printf("⚠️ Tool discovery completed with %d degraded backend(s)", len(degraded))
+	}
+
 	// Register aggregated tools with the MCP server
 	g.registerAggregatedTools()
 
 	return nil
 }
 
-// registerAggregatedTools registers all aggregated tools with the MCP server
+// registerAggregatedTools syncs the MCP server with the aggregated tools, only adding tools that are
+// new or changed and removing ones that disappeared, so a no-op refresh leaves the server untouched
 func (g *MCPHelper) registerAggregatedTools() {
-	g.toolsLock.RLock()
-	defer g.toolsLock.RUnlock()
+	g.toolsLock.Lock()
 
+	var register []server.ServerTool
+	current := make(map[string]bool, len(g.aggregatedTools))
 	for _, tool := range g.aggregatedTools {
+		current[tool.Name] = true
+		hash := toolHash(tool)
+		if g.registeredTools[tool.Name] == hash {
+			continue
+		}
+		g.registeredTools[tool.Name] = hash
+
 		// Create a closure to capture the tool name for routing
 		toolName := tool.Name
-		g.mcpServer.AddTool(tool, func(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
-			return g.routeToolCall(ctx, toolName, req)
+		register = append(register, server.ServerTool{
+			Tool: tool,
+			Handler: func(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+				return g.routeToolCall(ctx, toolName, req)
+			},
 		})
 	}
 
-	log.Printf("Registered %d aggregated tools with MCP server", len(g.aggregatedTools))
+	var remove []string
+	for name := range g.registeredTools {
+		if !current[name] {
+			remove = append(remove, name)
+			delete(g.registeredTools, name)
+		}
+	}
+	total := len(g.aggregatedTools)
+	g.toolsLock.Unlock()
+
+	// mcpServer sends notifications/tools/list_changed to every connected session on each
+	// add or delete (WithToolCapabilities(true)), so only real changes reach clients.
+	// Registrations notify sessions, so they are made outside the lock.
+	if len(remove) > 0 {
+		g.mcpServer.DeleteTools(remove...)
+	}
+	if len(register) > 0 {
+		g.mcpServer.AddTools(register...)
+	}
+	if len(remove) > 0 || len(register) > 0 {
+		log.Printf("📣 Tool list changed, notified connected sessions with tools/list_changed")
+	}
+
+	log.Printf("Registered %d aggregated tools with MCP server (%d added or changed, %d removed)",
+		total, len(register), len(remove))
+}
+
+// toolHash is a stable hash of a tool's full definition - name, description, schemas and annotations
+func toolHash(tool mcp.Tool) string {
+	var definition any
+	data, err := json.Marshal(tool)
+	if err == nil {
+		err = json.Unmarshal(data, &definition)
+	}
+	if err == nil {
+		data, err = extProc.CanonicalJSON(definition)
+	}
+	if err != nil {
+		// Unhashable definitions are always treated as changed
+		log.Printf("⚠️ Failed to hash tool %s: %v", tool.Name, err)
+		return ""
+	}
+
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+func (g *MCPHelper) routeToolCall(ctx context.Context, toolName string, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	if !g.IsToolVisible(toolName, principalFromContext(ctx)) {
+		return mcp.NewToolResultError(fmt.Sprintf("Unknown tool: %s", toolName)), nil
+	}
+
+	// ext-proc sets x-mcp-server on every call it routes, so without it Envoy isn't in the path
+	target := req.Header.Get("x-mcp-server")
+	if target == "" {
+		if standaloneMode {
+			return g.forwardToolCall(ctx, toolName, req)
+		}
+		log.Printf("❌ Tool call %s reached helper without Envoy routing", toolName)
+		return mcp.NewToolResultError(fmt.Sprintf(
+			"Tool %s can't be called: this helper was reached directly rather than through the Envoy gateway. "+
+				"Connect through the Envoy listener, or run the helper with STANDALONE_MODE=true to forward tool calls itself.",
+			toolName)), nil
+	}
+
+	// Routed by ext-proc but Envoy sent it here, so there's no route for the target
+	log.Printf("❌ Tool call %s was routed to %s but reached helper, check the Envoy route for x-mcp-server=%s", toolName, target, target)
+	return mcp.NewToolResultError(fmt.Sprintf(
+		"Tool %s can't be called: the gateway has no route to backend %s. Check the Envoy route configuration.",
+		toolName, target)), nil
 }
 
-func (g *MCPHelper) routeToolCall(_ context.Context, toolName string, _ mcp.CallToolRequest) (*mcp.CallToolResult, error) {
-	log.Printf("❌ Tool call reached helper unexpectedly: %s (should be routed by Envoy)", toolName)
-	return mcp.NewToolResultError(fmt.Sprintf("Tool call %s reached helper - this should be handled by Envoy routing", toolName)), nil
+// forwardToolCall calls a backend tool over the session's own backend connection, for standalone mode and helper_call
+func (g *MCPHelper) forwardToolCall(ctx context.Context, toolName string, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	session := server.ClientSessionFromContext(ctx)
+	if session == nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Tool %s can't be forwarded without a client session", toolName)), nil
+	}
+
+	g.connectionsLock.RLock()
+	connections, exists := g.clientConnections[session.SessionID()]
+	g.connectionsLock.RUnlock()
+	if !exists {
+		return mcp.NewToolResultError(fmt.Sprintf("Tool %s can't be forwarded, no backend connections for this session yet", toolName)), nil
+	}
+
+	target, backendToolName, found := g.ToolRoute(toolName)
+	if !found {
+		target, backendToolName, found = extProc.StripToolPrefix(toolName)
+	}
+	if !found {
+		return mcp.NewToolResultError(fmt.Sprintf("Tool %s doesn't belong to any backend", toolName)), nil
+	}
+	if g.IsBackendInMaintenance(target) {
+		return mcp.NewToolResultError(fmt.Sprintf("Backend %s is temporarily unavailable for maintenance, try again later", target)), nil
+	}
+	if _, unavailable := g.BackendUnavailable(target); unavailable {
+		return mcp.NewToolResultError(fmt.Sprintf("Backend %s is unavailable: it failed to initialize when the helper started", target)), nil
+	}
+	backendClient := connections.Clients[target]
+	if backendClient == nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Tool %s can't be forwarded, %s is not connected", toolName, target)), nil
+	}
+
+	// The rate limit, argument validation and response timeout apply as if ext-proc had routed the call
+	request := map[string]any{
+		"jsonrpc": mcp.JSONRPC_VERSION,
+		"method":  string(mcp.MethodToolsCall),
+		"params":  map[string]any{"name": toolName, "arguments": req.GetArguments()},
+	}
+	timeout, err := g.toolCallPolicies.CheckForwardedToolCall(session.SessionID(), principalFromContext(ctx), toolName, target, request)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	log.Printf("➡️ Forwarding %s to %s as %s", toolName, target, backendToolName)
+	backendReq := mcp.CallToolRequest{}
+	backendReq.Params.Name = backendToolName
+	backendReq.Params.Arguments = req.Params.Arguments
+	backendReq.Params.Meta = req.Params.Meta
+	result, err := backendClient.CallTool(ctx, backendReq)
+	if err != nil {
+		if timeout > 0 && errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			return mcp.NewToolResultError(fmt.Sprintf("Backend %s did not respond to %s within %s", target, toolName, timeout)), nil
+		}
+		return nil, err
+	}
+
+	encoded, err := json.Marshal(result)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode %s result: %w", toolName, err)
+	}
+	if err := g.toolCallPolicies.CheckForwardedToolResult(session.SessionID(), toolName, target, encoded); err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	return result, nil
 }
 
 // createClientBackendConnection creates and initializes a client connection to a backend server
-func (g *MCPHelper) createClientBackendConnection(ctx context.Context, clientSessionID string, serverName string, serverURL string) (*client.Client, string, error) {
+func (g *MCPHelper) createClientBackendConnection(ctx context.Context, clientSessionID string, backend BackendConfig) (*client.Client, string, error) {
+	serverName := backend.Name
 	log.Printf("🔗 Creating dedicated %s connection for client %s", serverName, clientSessionID)
 
+	// Wait for a session-creation slot so connection bursts don't stampede the backend
+	release, err := g.acquireInitSlot(ctx, serverName)
+	if err != nil {
+		return nil, "", err
+	}
+	defer release()
+
+	// Transient failures such as a backend restarting are retried with backoff
+	mcpClient, serverInfo, err := g.dialBackendWithRetry(ctx, clientSessionID, backend)
+	if err != nil {
+		return nil, "", err
+	}
+
+	// Extract the session ID from the initialized client
+	// An empty session ID means "no session" for stateless backends; calls are routed without one
+	sessionID := mcpClient.GetSessionId()
+	if sessionID == "" {
+		if g.backendRequiresSession(serverName) {
+			mcpClient.Close()
+			return nil, "", fmt.Errorf("failed to get session ID from %s - session ID is empty", serverName)
+		}
+		log.Printf("Backend %s is stateless, client %s has no backend session", serverName, clientSessionID)
+	}
+
+	log.Printf("✅ Client %s connected to %s: %s with session ID: %s",
+		clientSessionID, serverName, serverInfo.ServerInfo.Name, sessionID)
+
+	return mcpClient, sessionID, nil
+}
+
+// dialBackend connects a new client to a backend for a client session and initializes it,
+// closing the client again if initialization fails
+func (g *MCPHelper) dialBackend(ctx context.Context, clientSessionID string, backend BackendConfig) (*client.Client, *mcp.InitializeResult, error) {
+	serverName := backend.Name
+
 	// Create HTTP transport
-	httpTransport, err := transport.NewStreamableHTTP(serverURL)
+	var transportOptions []transport.StreamableHTTPCOption
+	if backendNotificationStream {
+		transportOptions = append(transportOptions, transport.WithContinuousListening())
+	}
+	httpTransport, err := transport.NewStreamableHTTP(backend.URL, transportOptions...)
 	if err != nil {
-		return nil, "", fmt.Errorf("failed to create HTTP transport for %s: %w", serverName, err)
+		return nil, nil, fmt.Errorf("failed to create HTTP transport for %s: %w", serverName, err)
 	}
 
-	// Create client
+	// Create client, following the backend's tool list changes for this client's session.
+	// The client outlives this call, so its notification stream isn't tied to ctx.
 	mcpClient := client.NewClient(httpTransport)
+	mcpClient.OnNotification(func(notification mcp.JSONRPCNotification) {
+		if notification.Method == mcp.MethodNotificationToolsListChanged {
+			go g.handleBackendToolsChanged(serverName, clientSessionID)
+		}
+	})
+	if err := mcpClient.Start(context.Background()); err != nil {
+		return nil, nil, fmt.Errorf("failed to start %s client: %w", serverName, err)
+	}
 
-	// Initialize with timeout
-	initCtx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	// Initialize with the backend's own timeout when it sets one
+	initCtx, cancel := context.WithTimeout(ctx, backend.initTimeout(g.timeouts.Init))
 	defer cancel()
 
 	// Initialize the connection
@@ -518,39 +1618,114 @@ func (g *MCPHelper) createClientBackendConnection(ctx context.Context, clientSes
 		Version: "1.0.0",
 	}
 	initRequest.Params.Capabilities = mcp.ClientCapabilities{}
+	applyInitParams(backend, &initRequest.Params)
 
 	serverInfo, err := mcpClient.Initialize(initCtx, initRequest)
 	if err != nil {
-		return nil, "", fmt.Errorf("failed to initialize %s: %w", serverName, err)
+		mcpClient.Close()
+		return nil, nil, fmt.Errorf("failed to initialize %s: %w", serverName, err)
 	}
+	return mcpClient, serverInfo, nil
+}
 
-	// Extract the session ID from the initialized client
-	sessionID := mcpClient.GetSessionId()
-	if sessionID == "" {
-		return nil, "", fmt.Errorf("failed to get session ID from %s - session ID is empty", serverName)
+// newInitSlots creates a session-creation semaphore per backend, or nil when unlimited
+func newInitSlots(backends ...string) map[string]chan struct{} {
+	if backendInitConcurrency <= 0 {
+		return nil
 	}
+	log.Printf("Limiting session creation to %d concurrent initializes per backend", backendInitConcurrency)
 
-	log.Printf("✅ Client %s connected to %s: %s with session ID: %s",
-		clientSessionID, serverName, serverInfo.ServerInfo.Name, sessionID)
+	slots := make(map[string]chan struct{}, len(backends))
+	for _, backend := range backends {
+		slots[backend] = make(chan struct{}, backendInitConcurrency)
+	}
+	return slots
+}
 
-	return mcpClient, sessionID, nil
+// acquireInitSlot waits up to the init queue timeout for a session-creation slot on a backend
+func (g *MCPHelper) acquireInitSlot(ctx context.Context, serverName string) (func(), error) {
+	slots, limited := g.initSlots[serverName]
+	if !limited {
+		return func() {}, nil
+	}
+
+	queueCtx, cancel := context.WithTimeout(ctx, g.timeouts.InitQueue)
+	defer cancel()
+
+	select {
+	case slots <- struct{}{}:
+		return func() { <-slots }, nil
+	case <-queueCtx.Done():
+		log.Printf("🚦 Timed out waiting for a %s session-creation slot (%d in flight)", serverName, len(slots))
+		return nil, fmt.Errorf("too many concurrent session creations for %s: %w", serverName, queueCtx.Err())
+	}
+}
+
+// BroadcastNotification forwards a client notification to each of the session's backend clients
+// (implements extProc.NotificationBroadcaster interface)
+func (g *MCPHelper) BroadcastNotification(ctx context.Context, helperSessionID, method string, params map[string]any) error {
+	g.connectionsLock.RLock()
+	connections, exists := g.clientConnections[helperSessionID]
+	g.connectionsLock.RUnlock()
+	if !exists {
+		return fmt.Errorf("no backend connections for session %s", helperSessionID)
+	}
+
+	notification := mcp.JSONRPCNotification{JSONRPC: mcp.JSONRPC_VERSION}
+	notification.Method = method
+	if params != nil {
+		paramBytes, err := json.Marshal(params)
+		if err != nil {
+			return fmt.Errorf("failed to encode notification params: %w", err)
+		}
+		if err := json.Unmarshal(paramBytes, &notification.Params); err != nil {
+			return fmt.Errorf("failed to decode notification params: %w", err)
+		}
+	}
+
+	sendCtx, cancel := context.WithTimeout(ctx, g.timeouts.Init)
+	defer cancel()
+
+	var errs []error
+	for name, backendClient := range connections.Clients {
+		if backendClient == nil {
+			continue
+		}
+		if err := backendClient.GetTransport().SendNotification(sendCtx, notification); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", name, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// backendRequiresSession reports whether a backend must return a session ID on initialize
+func (g *MCPHelper) backendRequiresSession(serverName string) bool {
+	backend, ok := g.findBackend(serverName)
+	return !ok || backend.RequiresSession
 }
 
 // handleHelperInfo handles the helper_info tool
 func (g *MCPHelper) handleHelperInfo(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
-	g.toolsLock.RLock()
-	toolCount := len(g.aggregatedTools)
-	g.toolsLock.RUnlock()
+	// One snapshot so the tool count and degraded backends always come from the same aggregation
+	tools := g.snapshotTools()
 
 	g.connectionsLock.RLock()
 	connectionCount := len(g.clientConnections)
 	g.connectionsLock.RUnlock()
 
+	backendURLs := make([]string, 0, len(g.backends))
+	for _, backend := range g.backends {
+		backendURLs = append(backendURLs, redactURL(backend.URL))
+	}
+
 	info := map[string]interface{}{
 		"helper_name":        "MCP Helper",
 		"version":            "1.0.0",
-		"backend_servers":    []string{server1URL, server2URL},
-		"aggregated_tools":   toolCount,
+		"backend_servers":    backendURLs,
+		"aggregated_tools":   tools.toolCount,
+		"aggregated_prompts": g.promptCount(),
+		"degraded_backends":  tools.degradedBackends,
+		"warnings":           degradedWarnings(tools.degradedBackends),
 		"active_connections": connectionCount,
 		"status":             "running",
 		"session_management": "per-client backend connections",
@@ -559,3 +1734,62 @@ func (g *MCPHelper) handleHelperInfo(ctx context.Context, req mcp.CallToolReques
 
 	return mcp.NewToolResultText(fmt.Sprintf("Helper Info: %+v", info)), nil
 }
+
+// handleHelperStatus handles the helper_status tool
+func (g *MCPHelper) handleHelperStatus(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	degradedBackends := g.getDegradedBackends()
+
+	status := "ok"
+	if len(degradedBackends) > 0 {
+		status = "degraded"
+	}
+
+	result := map[string]any{
+		"status":            status,
+		"degraded_backends": degradedBackends,
+		"warnings":          degradedWarnings(degradedBackends),
+	}
+
+	text, err := json.Marshal(result)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode helper status: %w", err)
+	}
+	return mcp.NewToolResultStructured(result, string(text)), nil
+}
+
+// toolSnapshot is a point-in-time view of the aggregated tool state
+type toolSnapshot struct {
+	toolCount        int
+	degradedBackends map[string]string // backend name -> discovery error
+}
+
+// snapshotTools copies the aggregated tool state under a single read lock, so a concurrent
+// aggregation or refresh is seen either entirely or not at all
+func (g *MCPHelper) snapshotTools() toolSnapshot {
+	g.toolsLock.RLock()
+	defer g.toolsLock.RUnlock()
+
+	snapshot := toolSnapshot{
+		toolCount:        len(g.aggregatedTools),
+		degradedBackends: make(map[string]string, len(g.degradedBackends)),
+	}
+	for name, reason := range g.degradedBackends {
+		snapshot.degradedBackends[name] = reason
+	}
+	return snapshot
+}
+
+// getDegradedBackends returns a copy of the backends that failed tool discovery and why
+func (g *MCPHelper) getDegradedBackends() map[string]string {
+	return g.snapshotTools().degradedBackends
+}
+
+// degradedWarnings describes each degraded backend for clients, sorted for stable output
+func degradedWarnings(degraded map[string]string) []string {
+	warnings := make([]string, 0, len(degraded))
+	for name, reason := range degraded {
+		warnings = append(warnings, fmt.Sprintf("backend %s is unavailable, its tools are not listed: %s", name, reason))
+	}
+	sort.Strings(warnings)
+	return warnings
+}
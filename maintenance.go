@@ -0,0 +1,77 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+)
+
+// SetBackendMaintenance puts a backend into or out of maintenance. Requests to a backend in maintenance
+// get a "temporarily unavailable" error instead of being routed; its config and sessions are kept.
+func (g *MCPHelper) SetBackendMaintenance(name string, on bool) error {
+	if _, ok := g.findBackend(name); !ok {
+		return fmt.Errorf("unknown backend: %s", name)
+	}
+
+	g.maintenanceLock.Lock()
+	defer g.maintenanceLock.Unlock()
+	if on {
+		g.maintenance[name] = true
+		log.Printf("🚧 %s is in maintenance", name)
+	} else {
+		delete(g.maintenance, name)
+		log.Printf("✅ %s is out of maintenance", name)
+	}
+	return nil
+}
+
+// IsBackendInMaintenance implements extProc.BackendMaintenance
+func (g *MCPHelper) IsBackendInMaintenance(backend string) bool {
+	g.maintenanceLock.RLock()
+	defer g.maintenanceLock.RUnlock()
+	return g.maintenance[backend]
+}
+
+// backendsInMaintenance returns the backends in maintenance, sorted
+func (g *MCPHelper) backendsInMaintenance() []string {
+	g.maintenanceLock.RLock()
+	defer g.maintenanceLock.RUnlock()
+
+	names := make([]string, 0, len(g.maintenance))
+	for name := range g.maintenance {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// handleMaintenance lists backends in maintenance on GET, and on POST sets a backend's maintenance
+// mode from a {"backend": "...", "maintenance": true|false} body
+func (g *MCPHelper) handleMaintenance(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+	case http.MethodPost:
+		var body struct {
+			Backend     string `json:"backend"`
+			Maintenance *bool  `json:"maintenance"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil || body.Maintenance == nil {
+			http.Error(w, `Expected a {"backend": "...", "maintenance": true|false} body`, http.StatusBadRequest)
+			return
+		}
+		if err := g.SetBackendMaintenance(body.Backend, *body.Maintenance); err != nil {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(map[string][]string{"maintenance": g.backendsInMaintenance()}); err != nil {
+		log.Printf("Failed to write maintenance status: %v", err)
+	}
+}
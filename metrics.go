@@ -0,0 +1,73 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// Backend initialize latency during session creation, by backend
+var backendInitDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+	Name:    "mcp_helper_backend_init_duration_seconds",
+	Help:    "Time to create and initialize a backend connection for a client session.",
+	Buckets: prometheus.DefBuckets,
+}, []string{"backend"})
+
+// Backend initializes that failed without a retry because the backend's retry budget was spent
+var retryBudgetExhaustedTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "mcp_helper_retry_budget_exhausted_total",
+	Help: "Backend initialize retries skipped because the backend's retry budget was exhausted.",
+}, []string{"backend"})
+
+// registerSessionMetrics exposes the helper's live session count
+func (g *MCPHelper) registerSessionMetrics() {
+	promauto.NewGaugeFunc(prometheus.GaugeOpts{
+		Name: "mcp_helper_active_sessions",
+		Help: "Helper sessions with a backend session mapping.",
+	}, func() float64 {
+		g.sessionLock.RLock()
+		defer g.sessionLock.RUnlock()
+		return float64(len(g.sessionMappings))
+	})
+}
+
+// observeBackendInit records how long a backend initialize took
+func observeBackendInit(backend string, start time.Time) {
+	backendInitDuration.WithLabelValues(backend).Observe(time.Since(start).Seconds())
+}
+
+// What happens when the metrics listener can't start or stops: "degrade" keeps serving MCP and
+// ext-proc without metrics, "fail" exits so the problem can't go unnoticed
+var metricsFailureMode = getEnv("METRICS_FAILURE_MODE", "degrade")
+
+// serveMetrics serves Prometheus metrics on their own port, kept off the MCP listener
+// so scrapes need no MCP authentication
+func serveMetrics(port string) {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.Handler())
+
+	lis, err := net.Listen("tcp", ":"+port)
+	if err != nil {
+		metricsFailed(fmt.Errorf("failed to listen on metrics port %s: %w", port, err))
+		return
+	}
+
+	log.Printf("Metrics endpoint: http://localhost:%s/metrics", port)
+	if err := http.Serve(lis, mux); err != nil {
+		metricsFailed(fmt.Errorf("metrics server error: %w", err))
+	}
+}
+
+// metricsFailed applies METRICS_FAILURE_MODE to a metrics server failure
+func metricsFailed(err error) {
+	if metricsFailureMode == "fail" {
+		log.Fatalf("%v", err)
+	}
+	log.Printf("⚠️⚠️⚠️ METRICS UNAVAILABLE: %v - MCP and ext-proc keep serving (METRICS_FAILURE_MODE=%s)", err, metricsFailureMode)
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"github.com/mark3labs/mcp-go/client"
+	"github.com/mark3labs/mcp-go/client/transport"
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// How ext-proc treats tool results that don't match the tool's declared output schema: "off", "log" or "reject"
+var outputSchemaValidation = getEnv("OUTPUT_SCHEMA_VALIDATION", "off")
+
+// toolsListRequests numbers the helper's own tools/list requests, kept apart from the client's request IDs
+var toolsListRequests atomic.Int64
+
+// listBackendTools lists every page of a backend's tools, keeping the output schemas they declare and
+// the timeout hints in their annotations, by backend tool name. mcp-go's ListTools drops outputSchema and
+// any non-standard annotation when decoding tools, so the pages are requested and decoded here.
+func listBackendTools(ctx context.Context, backendClient *client.Client) (*mcp.ListToolsResult, map[string]time.Duration, error) {
+	result := &mcp.ListToolsResult{}
+	timeouts := make(map[string]time.Duration)
+	var cursor mcp.Cursor
+	for {
+		request := mcp.ListToolsRequest{}
+		request.Params.Cursor = cursor
+
+		response, err := backendClient.GetTransport().SendRequest(ctx, transport.JSONRPCRequest{
+			JSONRPC: mcp.JSONRPC_VERSION,
+			ID:      mcp.NewRequestId(fmt.Sprintf("helper-tools-list-%d", toolsListRequests.Add(1))),
+			Method:  string(mcp.MethodToolsList),
+			Params:  request.Params,
+		})
+		if err != nil {
+			return nil, nil, transport.NewError(err)
+		}
+		if response.Error != nil {
+			return nil, nil, fmt.Errorf("tools/list failed: %s", response.Error.Message)
+		}
+
+		var page struct {
+			Tools      []json.RawMessage `json:"tools"`
+			NextCursor mcp.Cursor        `json:"nextCursor"`
+		}
+		if err := json.Unmarshal(response.Result, &page); err != nil {
+			return nil, nil, fmt.Errorf("failed to unmarshal tools/list response: %w", err)
+		}
+		for _, raw := range page.Tools {
+			var tool mcp.Tool
+			if err := json.Unmarshal(raw, &tool); err != nil {
+				return nil, nil, fmt.Errorf("failed to unmarshal tool: %w", err)
+			}
+			var declared struct {
+				OutputSchema json.RawMessage `json:"outputSchema"`
+				Annotations  struct {
+					TimeoutMs int64 `json:"timeoutMs"`
+				} `json:"annotations"`
+			}
+			if err := json.Unmarshal(raw, &declared); err == nil {
+				if len(declared.OutputSchema) > 0 && string(declared.OutputSchema) != "null" {
+					tool.RawOutputSchema = declared.OutputSchema
+				}
+				if declared.Annotations.TimeoutMs > 0 {
+					timeouts[tool.Name] = time.Duration(declared.Annotations.TimeoutMs) * time.Millisecond
+				}
+			}
+			result.Tools = append(result.Tools, tool)
+		}
+
+		if page.NextCursor == "" {
+			return result, timeouts, nil
+		}
+		select {
+		case <-ctx.Done():
+			return nil, nil, ctx.Err()
+		default:
+			cursor = page.NextCursor
+		}
+	}
+}
+
+// GetToolOutputSchema returns the output schema declared by an aggregated tool (implements ToolOutputSchemas interface)
+func (g *MCPHelper) GetToolOutputSchema(toolName string) (map[string]any, bool) {
+	g.toolsLock.RLock()
+	var raw json.RawMessage
+	for _, tool := range g.aggregatedTools {
+		if tool.Name == toolName {
+			raw = tool.RawOutputSchema
+			break
+		}
+	}
+	g.toolsLock.RUnlock()
+
+	if len(raw) == 0 {
+		return nil, false
+	}
+	var schema map[string]any
+	if err := json.Unmarshal(raw, &schema); err != nil {
+		return nil, false
+	}
+	return schema, true
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/mark3labs/mcp-go/client"
+	"github.com/mark3labs/mcp-go/client/transport"
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// probeResult is the outcome of a fresh initialize + tools/list against a backend
+type probeResult struct {
+	Backend         string   `json:"backend"`
+	URL             string   `json:"url"`
+	OK              bool     `json:"ok"`
+	Error           string   `json:"error,omitempty"`
+	ServerName      string   `json:"server_name,omitempty"`
+	ServerVersion   string   `json:"server_version,omitempty"`
+	ProtocolVersion string   `json:"protocol_version,omitempty"`
+	SessionID       string   `json:"session_id,omitempty"`
+	Tools           []string `json:"tools,omitempty"`
+	InitializeMs    int64    `json:"initialize_ms"`
+	ListToolsMs     int64    `json:"list_tools_ms"`
+	TotalMs         int64    `json:"total_ms"`
+}
+
+// handleProbe tests a backend from the helper's network vantage point with a throwaway connection.
+// The backend is named by a "backend" query parameter or a {"backend": "..."} body.
+func (g *MCPHelper) handleProbe(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	backend := r.URL.Query().Get("backend")
+	if backend == "" {
+		var body struct {
+			Backend string `json:"backend"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			http.Error(w, "Expected a backend query parameter or JSON body", http.StatusBadRequest)
+			return
+		}
+		backend = body.Backend
+	}
+
+	config, ok := g.findBackend(backend)
+	if !ok {
+		http.Error(w, "Unknown backend: "+backend, http.StatusNotFound)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), g.timeouts.Discovery)
+	defer cancel()
+
+	result := probeBackend(ctx, config)
+	log.Printf("🩺 Probed %s: ok %v in %dms", backend, result.OK, result.TotalMs)
+
+	w.Header().Set("Content-Type", "application/json")
+	if !result.OK {
+		w.WriteHeader(http.StatusBadGateway)
+	}
+	if err := json.NewEncoder(w).Encode(result); err != nil {
+		log.Printf("Failed to write probe result: %v", err)
+	}
+}
+
+// probeBackend runs initialize + tools/list over a fresh connection, bypassing sessions and caches
+func probeBackend(ctx context.Context, backend BackendConfig) (result probeResult) {
+	result = probeResult{Backend: backend.Name, URL: redactURL(backend.URL)}
+	start := time.Now()
+	defer func() {
+		result.TotalMs = time.Since(start).Milliseconds()
+	}()
+
+	httpTransport, err := transport.NewStreamableHTTP(backend.URL)
+	if err != nil {
+		result.Error = "failed to create transport: " + err.Error()
+		return result
+	}
+	probeClient := client.NewClient(httpTransport)
+	defer probeClient.Close()
+
+	initRequest := mcp.InitializeRequest{}
+	initRequest.Params.ProtocolVersion = mcp.LATEST_PROTOCOL_VERSION
+	initRequest.Params.ClientInfo = mcp.Implementation{
+		Name:    "MCP Helper (Probe)",
+		Version: "1.0.0",
+	}
+	applyInitParams(backend, &initRequest.Params)
+
+	initStart := time.Now()
+	serverInfo, err := probeClient.Initialize(ctx, initRequest)
+	result.InitializeMs = time.Since(initStart).Milliseconds()
+	if err != nil {
+		result.Error = "initialize failed: " + err.Error()
+		return result
+	}
+	result.ServerName = serverInfo.ServerInfo.Name
+	result.ServerVersion = serverInfo.ServerInfo.Version
+	result.ProtocolVersion = serverInfo.ProtocolVersion
+	result.SessionID = probeClient.GetSessionId()
+
+	listStart := time.Now()
+	tools, err := probeClient.ListTools(ctx, mcp.ListToolsRequest{})
+	result.ListToolsMs = time.Since(listStart).Milliseconds()
+	if err != nil {
+		result.Error = "tools/list failed: " + err.Error()
+		return result
+	}
+	for _, tool := range tools.Tools {
+		result.Tools = append(result.Tools, tool.Name)
+	}
+
+	result.OK = true
+	return result
+}
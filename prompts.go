@@ -0,0 +1,147 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	extProc "mcp-helper/ext-proc"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// aggregatePrompts lists prompts from every backend advertising the prompts capability and registers
+// them with mcpServer, named with the same prefix or tool group as the backend's tools. Backends without
+// prompts, or whose listing fails, simply contribute none.
+func (g *MCPHelper) aggregatePrompts() {
+	budgetCtx, cancel := context.WithTimeout(context.Background(), g.timeouts.DiscoveryBudget)
+	defer cancel()
+
+	var allPrompts []mcp.Prompt
+	for _, backend := range g.backends {
+		backendClient := g.startupClients[backend.Name]
+		if backendClient == nil {
+			continue
+		}
+		if backendClient.GetServerCapabilities().Prompts == nil {
+			log.Printf("%s doesn't support prompts, skipping", backend.Name)
+			continue
+		}
+
+		ctx, cancel := context.WithTimeout(budgetCtx, g.timeouts.Discovery)
+		result, err := backendClient.ListPrompts(ctx, mcp.ListPromptsRequest{})
+		cancel()
+		if err != nil {
+			log.Printf("⚠️ Failed to list prompts from %s, continuing without them: %v", backend.Name, err)
+			continue
+		}
+
+		prefix := extProc.ToolPrefix(backend.Name)
+		for _, prompt := range result.Prompts {
+			prompt.Name = prefix + prompt.Name
+			allPrompts = append(allPrompts, prompt)
+		}
+		log.Printf("%s contributed %d prompts", backend.Name, len(result.Prompts))
+	}
+
+	g.registerAggregatedPrompts(allPrompts)
+}
+
+// registerAggregatedPrompts syncs the MCP server with the aggregated prompts, only registering
+// prompts that are new or changed
+func (g *MCPHelper) registerAggregatedPrompts(prompts []mcp.Prompt) {
+	g.toolsLock.Lock()
+
+	var register []server.ServerPrompt
+	current := make(map[string]bool, len(prompts))
+	for _, prompt := range prompts {
+		current[prompt.Name] = true
+		hash := definitionHash(prompt.Name, prompt)
+		if g.promptHashes[prompt.Name] == hash {
+			continue
+		}
+		g.promptHashes[prompt.Name] = hash
+		register = append(register, server.ServerPrompt{
+			Prompt:  prompt,
+			Handler: g.routePromptGet,
+		})
+	}
+
+	var remove []string
+	for name := range g.promptHashes {
+		if !current[name] {
+			remove = append(remove, name)
+			delete(g.promptHashes, name)
+		}
+	}
+	g.toolsLock.Unlock()
+
+	// Registrations notify sessions with prompts/list_changed, so they are made outside the lock
+	if len(remove) > 0 {
+		g.mcpServer.DeletePrompts(remove...)
+	}
+	if len(register) > 0 {
+		g.mcpServer.AddPrompts(register...)
+	}
+
+	log.Printf("Registered %d aggregated prompts with MCP server (%d added or changed, %d removed)",
+		len(prompts), len(register), len(remove))
+}
+
+// promptCount returns the number of aggregated prompts
+func (g *MCPHelper) promptCount() int {
+	g.toolsLock.RLock()
+	defer g.toolsLock.RUnlock()
+	return len(g.promptHashes)
+}
+
+// routePromptGet handles prompts/get requests that reach the helper. ext-proc routes backend prompts
+// straight to the backend, so only standalone mode serves them here.
+func (g *MCPHelper) routePromptGet(ctx context.Context, req mcp.GetPromptRequest) (*mcp.GetPromptResult, error) {
+	name := req.Params.Name
+
+	target := req.Header.Get("x-mcp-server")
+	if target == "" {
+		if standaloneMode {
+			return g.forwardPromptGet(ctx, req)
+		}
+		log.Printf("❌ Prompt %s reached helper without Envoy routing", name)
+		return nil, fmt.Errorf("prompt %s can't be fetched: this helper was reached directly rather than through the Envoy gateway", name)
+	}
+
+	log.Printf("❌ Prompt %s was routed to %s but reached helper, check the Envoy route for x-mcp-server=%s", name, target, target)
+	return nil, fmt.Errorf("prompt %s can't be fetched: the gateway has no route to backend %s", name, target)
+}
+
+// forwardPromptGet fetches a backend prompt over the session's own backend connection, for standalone mode
+func (g *MCPHelper) forwardPromptGet(ctx context.Context, req mcp.GetPromptRequest) (*mcp.GetPromptResult, error) {
+	name := req.Params.Name
+
+	session := server.ClientSessionFromContext(ctx)
+	if session == nil {
+		return nil, fmt.Errorf("prompt %s can't be forwarded without a client session", name)
+	}
+
+	g.connectionsLock.RLock()
+	connections, exists := g.clientConnections[session.SessionID()]
+	g.connectionsLock.RUnlock()
+	if !exists {
+		return nil, fmt.Errorf("prompt %s can't be forwarded, no backend connections for this session yet", name)
+	}
+
+	target, backendPromptName, found := extProc.StripToolPrefix(name)
+	if !found {
+		return nil, fmt.Errorf("prompt %s doesn't belong to any backend", name)
+	}
+	backendClient := connections.Clients[target]
+	if backendClient == nil {
+		return nil, fmt.Errorf("prompt %s can't be forwarded, %s is not connected", name, target)
+	}
+
+	log.Printf("➡️ Standalone mode: forwarding prompt %s to %s as %s", name, target, backendPromptName)
+	backendReq := mcp.GetPromptRequest{}
+	backendReq.Params.Name = backendPromptName
+	backendReq.Params.Arguments = req.Params.Arguments
+	return backendClient.GetPrompt(ctx, backendReq)
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"context"
+	"log"
+	"time"
+)
+
+// StartSessionReaper evicts sessions older than ttl every interval until ctx is cancelled,
+// closing their backend clients. A ttl or interval of 0 disables eviction.
+func (g *MCPHelper) StartSessionReaper(ctx context.Context, interval, ttl time.Duration) {
+	if interval <= 0 || ttl <= 0 {
+		log.Println("Session reaper disabled, sessions are kept until shutdown")
+		return
+	}
+	log.Printf("Evicting sessions older than %s every %s", ttl, interval)
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case now := <-ticker.C:
+				g.reapSessions(now.Add(-ttl))
+			}
+		}
+	}()
+}
+
+// reapSessions removes session mappings and backend connections created before cutoff, and ends the
+// helper sessions themselves so their clients get 404 and re-initialize instead of calling into nothing.
+func (g *MCPHelper) reapSessions(cutoff time.Time) {
+	for _, helperSessionID := range g.sessionIDs.expire(cutoff) {
+		g.mcpServer.UnregisterSession(context.Background(), helperSessionID)
+	}
+
+	expiredMappings, expiredConnections := g.evictSessions(func(_ string, createdAt time.Time) bool {
+		return createdAt.Before(cutoff)
+	})
+	if expiredMappings > 0 || expiredConnections > 0 {
+		log.Printf("🧹 Evicted %d expired session mapping(s) and %d backend connection set(s)", expiredMappings, expiredConnections)
+	}
+}
+
+// endSession removes a helper session's mapping and closes its backend connections, for a session the
+// client deleted
+func (g *MCPHelper) endSession(helperSessionID string) {
+	mappings, connections := g.evictSessions(func(id string, _ time.Time) bool {
+		return id == helperSessionID
+	})
+	if mappings > 0 || connections > 0 {
+		log.Printf("🧹 Ended session %s, closing its backend connections", helperSessionID)
+	}
+}
+
+// evictSessions removes the session mappings and backend connections matching evict and closes the
+// connections' clients, returning how many of each were removed. The locks are taken one after the
+// other, never nested, so eviction can't deadlock with session creation, which nests them; clients are
+// closed outside them so a slow backend can't stall routing lookups.
+func (g *MCPHelper) evictSessions(evict func(helperSessionID string, createdAt time.Time) bool) (int, int) {
+	g.sessionLock.Lock()
+	var mappings int
+	for helperSessionID, mapping := range g.sessionMappings {
+		if evict(helperSessionID, mapping.CreatedAt) {
+			delete(g.sessionMappings, helperSessionID)
+			mappings++
+		}
+	}
+	g.sessionLock.Unlock()
+
+	g.connectionsLock.Lock()
+	var evicted []*ClientBackendConnections
+	for helperSessionID, connections := range g.clientConnections {
+		if evict(helperSessionID, connections.CreatedAt) {
+			delete(g.clientConnections, helperSessionID)
+			evicted = append(evicted, connections)
+		}
+	}
+	g.connectionsLock.Unlock()
+
+	for _, connections := range evicted {
+		if err := g.closeBackendConnections(connections); err != nil {
+			log.Printf("⚠️ Errors closing session %s: %v", connections.ClientSessionID, err)
+		}
+	}
+	return mappings, len(evicted)
+}
@@ -0,0 +1,170 @@
+package main
+
+import (
+	"context"
+	"maps"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/mark3labs/mcp-go/client"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+func TestEvictedSessionIsNotFound(t *testing.T) {
+	backend := newTestBackend(t, testTool("echo"))
+	helper := newTestHelper(t, testBackendConfig("server1", backend.URL))
+	helperServer := httptest.NewServer(helper.mcpHandler())
+	defer helperServer.Close()
+
+	mcpClient := connectTestClient(t, helperServer.URL)
+	helperSession := clientSessionID(t, mcpClient)
+	waitForSessionMapping(t, helper, helperSession)
+
+	ping := `{"jsonrpc":"2.0","id":2,"method":"ping"}`
+	if response := postJSONRPC(t, helperServer.URL, server.HeaderKeySessionID, helperSession, ping); response.StatusCode != http.StatusOK {
+		t.Fatalf("ping before eviction returned %d, want 200", response.StatusCode)
+	}
+
+	helper.reapSessions(time.Now().Add(time.Minute))
+
+	if _, ok := helper.GetSessionMapping(helperSession); ok {
+		t.Error("session mapping survived eviction")
+	}
+	if response := postJSONRPC(t, helperServer.URL, server.HeaderKeySessionID, helperSession, ping); response.StatusCode != http.StatusNotFound {
+		t.Errorf("ping after eviction returned %d, want 404", response.StatusCode)
+	}
+
+	// A client that re-initializes gets a working session again
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := connectTestClient(t, helperServer.URL).Ping(ctx); err != nil {
+		t.Errorf("ping on a new session = %v", err)
+	}
+}
+
+func TestTerminatedSessionReleasesBackends(t *testing.T) {
+	backend := newTestBackend(t, testTool("echo"))
+	helper := newTestHelper(t, testBackendConfig("server1", backend.URL))
+	helperServer := httptest.NewServer(helper.mcpHandler())
+	defer helperServer.Close()
+
+	mcpClient := connectTestClient(t, helperServer.URL)
+	helperSession := clientSessionID(t, mcpClient)
+	waitForSessionMapping(t, helper, helperSession)
+
+	request, err := http.NewRequest(http.MethodDelete, helperServer.URL, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	request.Header.Set(server.HeaderKeySessionID, helperSession)
+	response, err := http.DefaultClient.Do(request)
+	if err != nil {
+		t.Fatal(err)
+	}
+	response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		t.Fatalf("DELETE returned %d, want 200", response.StatusCode)
+	}
+
+	// Released on the DELETE itself, not left for the reaper
+	if _, ok := helper.GetSessionMapping(helperSession); ok {
+		t.Error("session mapping survived termination")
+	}
+	helper.connectionsLock.RLock()
+	_, connected := helper.clientConnections[helperSession]
+	helper.connectionsLock.RUnlock()
+	if connected {
+		t.Error("backend connections survived termination")
+	}
+}
+
+func TestSessionIndexesStayConsistent(t *testing.T) {
+	backend := newTestBackend(t, testTool("echo"))
+	helper := newTestHelper(t, testBackendConfig("server1", backend.URL))
+	helperServer := httptest.NewServer(helper.mcpHandler())
+	defer helperServer.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	connect := func() (*client.Client, string) {
+		mcpClient := connectTestClient(t, helperServer.URL)
+		helperSession := clientSessionID(t, mcpClient)
+		waitForSessionMapping(t, helper, helperSession)
+		return mcpClient, helperSession
+	}
+	terminated, terminatedSession := connect()
+	_, reinitialized := connect()
+	_, expired := connect()
+	assertSessionIndexesConsistent(t, helper, terminatedSession, reinitialized, expired)
+
+	if err := helper.handleInitialization(ctx, reinitialized, ""); err != nil {
+		t.Fatalf("handleInitialization() error = %v", err)
+	}
+	assertSessionIndexesConsistent(t, helper, terminatedSession, reinitialized, expired)
+
+	if err := terminated.Close(); err != nil {
+		t.Fatalf("closing client: %v", err)
+	}
+	assertSessionIndexesConsistent(t, helper, reinitialized, expired)
+
+	cutoff := time.Now()
+	_, survivor := connect()
+	helper.reapSessions(cutoff)
+	assertSessionIndexesConsistent(t, helper, survivor)
+}
+
+// assertSessionIndexesConsistent checks that exactly the want helper sessions are live, mapped and
+// connected, that each mapping's backend sessions are the ones its connections track, and that no
+// backend session maps back to more than one helper session
+func assertSessionIndexesConsistent(t *testing.T, helper *MCPHelper, want ...string) {
+	t.Helper()
+
+	helper.sessionLock.RLock()
+	defer helper.sessionLock.RUnlock()
+	helper.connectionsLock.RLock()
+	defer helper.connectionsLock.RUnlock()
+	helper.sessionIDs.lock.Lock()
+	defer helper.sessionIDs.lock.Unlock()
+
+	if len(helper.sessionMappings) != len(want) || len(helper.clientConnections) != len(want) || len(helper.sessionIDs.live) != len(want) {
+		t.Errorf("%d mappings, %d connection sets and %d live sessions, want %d of each",
+			len(helper.sessionMappings), len(helper.clientConnections), len(helper.sessionIDs.live), len(want))
+	}
+	owners := make(map[string]string) // backend session -> helper session
+	for _, helperSession := range want {
+		mapping, mapped := helper.sessionMappings[helperSession]
+		connections, connected := helper.clientConnections[helperSession]
+		_, live := helper.sessionIDs.live[helperSession]
+		if !mapped || !connected || !live {
+			t.Errorf("session %s: mapped=%v connected=%v live=%v, want all", helperSession, mapped, connected, live)
+			continue
+		}
+		if connections.ClientSessionID != helperSession {
+			t.Errorf("session %s holds the connections of %s", helperSession, connections.ClientSessionID)
+		}
+		if !maps.Equal(mapping.BackendSessions, connections.SessionIDs) {
+			t.Errorf("session %s maps to %v but its connections track %v", helperSession, mapping.BackendSessions, connections.SessionIDs)
+		}
+		for name, backendSession := range mapping.BackendSessions {
+			if owner, taken := owners[backendSession]; taken {
+				t.Errorf("%s session %s maps back to both %s and %s", name, backendSession, owner, helperSession)
+			}
+			owners[backendSession] = helperSession
+		}
+	}
+}
+
+func TestUnknownSessionIsNotFound(t *testing.T) {
+	helper := newTestHelper(t)
+	helperServer := httptest.NewServer(helper.mcpHandler())
+	defer helperServer.Close()
+
+	// Well-formed, but never issued by this helper - as after a restart
+	response := postJSONRPC(t, helperServer.URL, server.HeaderKeySessionID,
+		"mcp-session-8a6e0804-2bd0-4672-b79d-d97027f9071a", `{"jsonrpc":"2.0","id":1,"method":"ping"}`)
+	if response.StatusCode != http.StatusNotFound {
+		t.Errorf("ping on an unknown session returned %d, want 404", response.StatusCode)
+	}
+}
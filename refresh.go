@@ -0,0 +1,325 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"time"
+
+	extProc "mcp-helper/ext-proc"
+
+	"github.com/mark3labs/mcp-go/client"
+	"github.com/mark3labs/mcp-go/client/transport"
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// toolDelta lists the prefixed tool names changed by a backend refresh
+type toolDelta struct {
+	Backend string   `json:"backend"`
+	Added   []string `json:"added"`
+	Removed []string `json:"removed"`
+	Updated []string `json:"updated"`
+}
+
+// changed reports whether the refresh added, removed or updated any tool
+func (d toolDelta) changed() bool {
+	return len(d.Added)+len(d.Removed)+len(d.Updated) > 0
+}
+
+// discoverBackendTools lists a backend's tools over a fresh connection, prefixed as in aggregation, along
+// with the timeout hints they declare
+func discoverBackendTools(ctx context.Context, backend BackendConfig) ([]mcp.Tool, map[string]time.Duration, error) {
+	httpTransport, err := transport.NewStreamableHTTP(backend.URL)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to create HTTP transport for %s: %w", backend.Name, err)
+	}
+	discoveryClient := client.NewClient(httpTransport)
+	defer discoveryClient.Close()
+
+	initRequest := mcp.InitializeRequest{}
+	initRequest.Params.ProtocolVersion = mcp.LATEST_PROTOCOL_VERSION
+	initRequest.Params.ClientInfo = mcp.Implementation{
+		Name:    "MCP Helper (Refresh)",
+		Version: "1.0.0",
+	}
+	applyInitParams(backend, &initRequest.Params)
+	if _, err := discoveryClient.Initialize(ctx, initRequest); err != nil {
+		return nil, nil, fmt.Errorf("failed to initialize %s: %w", backend.Name, err)
+	}
+
+	result, hints, err := listBackendTools(ctx, discoveryClient)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to list tools from %s: %w", backend.Name, err)
+	}
+
+	prefix := extProc.ToolPrefix(backend.Name)
+	tools := make([]mcp.Tool, 0, len(result.Tools))
+	timeouts := make(map[string]time.Duration, len(hints))
+	for _, tool := range result.Tools {
+		prefixedTool := limitToolSchema(tool, maxToolSchemaBytes)
+		prefixedTool.Name = prefix + tool.Name
+		tools = append(tools, prefixedTool)
+		if timeout, ok := hints[tool.Name]; ok {
+			timeouts[prefixedTool.Name] = timeout
+		}
+	}
+	return tools, timeouts, nil
+}
+
+// RefreshTools re-lists tools from every backend over fresh connections and re-registers what changed,
+// so connected clients get notifications/tools/list_changed. A backend that can't be reached keeps its
+// previous tools; its error is returned alongside the deltas of the backends that were refreshed.
+// Concurrent refreshes share one run.
+func (g *MCPHelper) RefreshTools(ctx context.Context) ([]toolDelta, error) {
+	var deltas []toolDelta
+	shared, err := g.aggregation.do("refresh", func() error {
+		var err error
+		deltas, err = g.runRefresh(ctx)
+		return err
+	})
+	if shared {
+		log.Println("Joined tool refresh already in progress")
+	}
+	return deltas, err
+}
+
+// runRefresh discovers every backend's tools in parallel, as bounded as startup discovery, then swaps them in and registers the result once
+func (g *MCPHelper) runRefresh(ctx context.Context) ([]toolDelta, error) {
+	g.toolSetLock.Lock()
+	defer g.toolSetLock.Unlock()
+
+	ctx, cancel := context.WithTimeout(ctx, g.timeouts.DiscoveryBudget)
+	defer cancel()
+
+	results := make([][]mcp.Tool, len(g.backends))
+	timeouts := make([]map[string]time.Duration, len(g.backends))
+	errs := g.discoverEach(ctx, g.backendNameList(), func(ctx context.Context, i int) error {
+		var err error
+		results[i], timeouts[i], err = discoverBackendTools(ctx, g.backends[i])
+		return err
+	})
+
+	var deltas []toolDelta
+	for i, backend := range g.backends {
+		if errs[i] != nil {
+			log.Printf("⚠️ Failed to refresh tools for %s, keeping its previous tools: %v", backend.Name, errs[i])
+			continue
+		}
+		g.setToolTimeouts(backend.Name, timeouts[i])
+		delta := g.swapBackendTools(backend.Name, results[i])
+		if delta.changed() {
+			log.Printf("🔄 Refreshed %s: %d added, %d removed, %d updated",
+				backend.Name, len(delta.Added), len(delta.Removed), len(delta.Updated))
+			deltas = append(deltas, delta)
+		}
+	}
+
+	g.registerAggregatedTools()
+	return deltas, errors.Join(errs...)
+}
+
+// handleBackendToolsChanged re-discovers a backend's tools after it sent notifications/tools/list_changed
+// on a client's backend session. Every client's session hears the same change, so notifications for one
+// backend are gathered for the debounce window and refreshed once, after which refreshChangedBackend
+// answers every session that heard one.
+func (g *MCPHelper) handleBackendToolsChanged(backend, helperSessionID string) {
+	log.Printf("📣 %s sent tools/list_changed on the session of client %s", backend, helperSessionID)
+
+	g.toolsChangedLock.Lock()
+	defer g.toolsChangedLock.Unlock()
+	sessions, pending := g.toolsChanged[backend]
+	if !pending {
+		sessions = make(map[string]bool)
+		g.toolsChanged[backend] = sessions
+		time.AfterFunc(g.timeouts.ToolsChanged, func() { g.refreshChangedBackend(backend) })
+	}
+	sessions[helperSessionID] = true
+}
+
+// refreshChangedBackend refreshes a backend whose tools/list_changed notifications have been gathered.
+// When the refresh re-registers changed tools every client is already notified; otherwise the
+// notification is forwarded to each client whose session it arrived on.
+func (g *MCPHelper) refreshChangedBackend(backend string) {
+	g.toolsChangedLock.Lock()
+	sessions := g.toolsChanged[backend]
+	delete(g.toolsChanged, backend)
+	g.toolsChangedLock.Unlock()
+
+	delta, err := g.refreshBackend(context.Background(), backend)
+	if err != nil {
+		log.Printf("⚠️ Failed to refresh tools for %s after tools/list_changed: %v", backend, err)
+	}
+	if delta.changed() {
+		return
+	}
+
+	for helperSessionID := range sessions {
+		if err := g.mcpServer.SendNotificationToSpecificClient(helperSessionID, mcp.MethodNotificationToolsListChanged, nil); err != nil {
+			log.Printf("⚠️ Failed to forward tools/list_changed from %s to client %s: %v", backend, helperSessionID, err)
+		}
+	}
+}
+
+// refreshBackend re-discovers one backend's tools and swaps them in, returning what changed
+func (g *MCPHelper) refreshBackend(ctx context.Context, backend string) (toolDelta, error) {
+	config, ok := g.findBackend(backend)
+	if !ok {
+		return toolDelta{}, fmt.Errorf("unknown backend: %s", backend)
+	}
+	g.toolSetLock.Lock()
+	defer g.toolSetLock.Unlock()
+
+	ctx, cancel := context.WithTimeout(ctx, g.timeouts.Discovery)
+	defer cancel()
+
+	tools, timeouts, err := discoverBackendTools(ctx, config)
+	if err != nil {
+		return toolDelta{}, err
+	}
+	g.setToolTimeouts(backend, timeouts)
+	delta := g.replaceBackendTools(backend, tools)
+	if delta.changed() {
+		log.Printf("🔄 Refreshed %s: %d added, %d removed, %d updated",
+			backend, len(delta.Added), len(delta.Removed), len(delta.Updated))
+	}
+	return delta, nil
+}
+
+// StartToolRefresh refreshes the aggregated tools every interval until ctx is cancelled.
+// An interval of 0 disables periodic refresh.
+func (g *MCPHelper) StartToolRefresh(ctx context.Context, interval time.Duration) {
+	if interval <= 0 {
+		log.Println("Periodic tool refresh disabled")
+		return
+	}
+	log.Printf("Refreshing tools from backends every %s", interval)
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if _, err := g.RefreshTools(ctx); err != nil {
+					log.Printf("⚠️ Periodic tool refresh incomplete: %v", err)
+				}
+			}
+		}
+	}()
+}
+
+// handleAdminRefresh refreshes the aggregated tools on demand, or only one backend's with ?backend=<name>
+// after it is redeployed, and reports what changed per backend
+func (g *MCPHelper) handleAdminRefresh(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var deltas []toolDelta
+	var err error
+	if backend := r.URL.Query().Get("backend"); backend != "" {
+		if _, ok := g.findBackend(backend); !ok {
+			http.Error(w, fmt.Sprintf("Unknown backend: %s", backend), http.StatusNotFound)
+			return
+		}
+		var delta toolDelta
+		if delta, err = g.refreshBackend(r.Context(), backend); err == nil && delta.changed() {
+			deltas = []toolDelta{delta}
+		}
+	} else {
+		deltas, err = g.RefreshTools(r.Context())
+	}
+	response := struct {
+		Changed []toolDelta `json:"changed"`
+		Error   string      `json:"error,omitempty"`
+	}{Changed: deltas}
+	if response.Changed == nil {
+		response.Changed = []toolDelta{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err != nil {
+		response.Error = err.Error()
+		w.WriteHeader(http.StatusBadGateway)
+	}
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		log.Printf("Failed to write refresh result: %v", err)
+	}
+}
+
+// replaceBackendTools swaps one backend's tools in the aggregated set and re-registers only what changed,
+// leaving every other backend's tools untouched
+func (g *MCPHelper) replaceBackendTools(backend string, tools []mcp.Tool) toolDelta {
+	delta := g.swapBackendTools(backend, tools)
+	g.registerAggregatedTools()
+	return delta
+}
+
+// swapBackendTools replaces one backend's tools in the aggregated set and returns what changed,
+// without registering the result with the MCP server
+func (g *MCPHelper) swapBackendTools(backend string, tools []mcp.Tool) toolDelta {
+	delta := toolDelta{Backend: backend, Added: []string{}, Removed: []string{}, Updated: []string{}}
+
+	g.toolsLock.Lock()
+	previous := make(map[string]string)
+	kept := make([]mcp.Tool, 0, len(g.aggregatedTools))
+	for _, tool := range g.aggregatedTools {
+		if g.toolBackends[tool.Name] == backend {
+			previous[tool.Name] = toolHash(tool)
+			continue
+		}
+		kept = append(kept, tool)
+	}
+	delete(g.degradedBackends, backend)
+	if _, was := g.unavailable[backend]; was {
+		log.Printf("✅ Backend %s is available again, new sessions will connect to it", backend)
+		delete(g.unavailable, backend)
+	}
+	for name, owner := range g.toolBackends {
+		if owner == backend {
+			delete(g.toolBackends, name)
+		}
+	}
+	accepted := make([]mcp.Tool, 0, len(tools))
+	for _, tool := range tools {
+		if owner, taken := g.toolBackends[tool.Name]; taken {
+			log.Printf("⚠️ %s lists %s, which %s already serves, skipping it", backend, tool.Name, owner)
+			continue
+		}
+		g.toolBackends[tool.Name] = backend
+		accepted = append(accepted, tool)
+	}
+	g.aggregatedTools = append(kept, accepted...)
+	g.toolsLock.Unlock()
+	tools = accepted
+
+	current := make(map[string]bool, len(tools))
+	for _, tool := range tools {
+		current[tool.Name] = true
+		hash, existed := previous[tool.Name]
+		switch {
+		case !existed:
+			delta.Added = append(delta.Added, tool.Name)
+		case hash != toolHash(tool):
+			delta.Updated = append(delta.Updated, tool.Name)
+		}
+	}
+	for name := range previous {
+		if !current[name] {
+			delta.Removed = append(delta.Removed, name)
+		}
+	}
+
+	sort.Strings(delta.Added)
+	sort.Strings(delta.Removed)
+	sort.Strings(delta.Updated)
+	return delta
+}
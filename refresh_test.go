@@ -0,0 +1,313 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	extProc "mcp-helper/ext-proc"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// recordingSession is an initialized client session that records the notifications sent to it
+type recordingSession struct {
+	id            string
+	notifications chan mcp.JSONRPCNotification
+}
+
+func newRecordingSession(id string) *recordingSession {
+	return &recordingSession{id: id, notifications: make(chan mcp.JSONRPCNotification, 16)}
+}
+
+func (s *recordingSession) Initialize()       {}
+func (s *recordingSession) Initialized() bool { return true }
+func (s *recordingSession) SessionID() string { return s.id }
+func (s *recordingSession) NotificationChannel() chan<- mcp.JSONRPCNotification {
+	return s.notifications
+}
+
+// drain returns how many notifications were sent to the session since the last drain
+func (s *recordingSession) drain() int {
+	count := 0
+	for {
+		select {
+		case <-s.notifications:
+			count++
+		default:
+			return count
+		}
+	}
+}
+
+func TestRefreshWithUnchangedToolsIsANoOp(t *testing.T) {
+	helper := newTestHelper(t)
+	session := newRecordingSession("client-1")
+	if err := helper.mcpServer.RegisterSession(context.Background(), session); err != nil {
+		t.Fatalf("RegisterSession() error = %v", err)
+	}
+
+	tools := []mcp.Tool{
+		mcp.NewTool("server1-echo", mcp.WithDescription("Echo"), mcp.WithString("text", mcp.Required())),
+		mcp.NewTool("server1-add", mcp.WithDescription("Add"), mcp.WithNumber("a"), mcp.WithNumber("b")),
+	}
+	first := helper.swapBackendTools("server1", tools)
+	helper.registerAggregatedTools()
+	if len(first.Added) != 2 {
+		t.Fatalf("first refresh added %v, want both tools", first.Added)
+	}
+	if session.drain() == 0 {
+		t.Fatal("first refresh sent no tools/list_changed")
+	}
+
+	second := helper.swapBackendTools("server1", tools)
+	helper.registerAggregatedTools()
+	if len(second.Added)+len(second.Removed)+len(second.Updated) != 0 {
+		t.Errorf("second refresh delta = %+v, want it empty", second)
+	}
+	if notifications := session.drain(); notifications != 0 {
+		t.Errorf("second refresh sent %d notifications, want none", notifications)
+	}
+	if len(helper.registeredTools) != 2 {
+		t.Errorf("registered tools = %v, want the same two tools", helper.registeredTools)
+	}
+}
+
+// annotatedBackend is a bare JSON-RPC backend listing one tool with a timeoutMs annotation, which mcp-go's
+// own tool types can't express. It counts the tools/list requests it answers.
+func annotatedBackend(t *testing.T) (*httptest.Server, *atomic.Int32) {
+	t.Helper()
+
+	var lists atomic.Int32
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var request struct {
+			ID     json.RawMessage `json:"id"`
+			Method string          `json:"method"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil || request.ID == nil {
+			w.WriteHeader(http.StatusAccepted)
+			return
+		}
+
+		result := `{}`
+		switch request.Method {
+		case "initialize":
+			result = `{"protocolVersion":"2025-03-26","capabilities":{"tools":{}},"serverInfo":{"name":"annotated","version":"1.0.0"}}`
+		case "tools/list":
+			lists.Add(1)
+			result = `{"tools":[` +
+				`{"name":"long_job","inputSchema":{"type":"object"},"annotations":{"readOnlyHint":false,"timeoutMs":300000}},` +
+				`{"name":"echo","inputSchema":{"type":"object"}}]}`
+		}
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprintf(w, `{"jsonrpc":"2.0","id":%s,"result":%s}`, request.ID, result)
+	}))
+	t.Cleanup(backend.Close)
+	return backend, &lists
+}
+
+func TestToolTimeoutHintsFromAnnotations(t *testing.T) {
+	backend, _ := annotatedBackend(t)
+	helper := newTestHelper(t, testBackendConfig("server1", backend.URL))
+
+	tools, timeouts, err := discoverBackendTools(context.Background(), testBackendConfig("server1", backend.URL))
+	if err != nil {
+		t.Fatalf("discoverBackendTools() error = %v", err)
+	}
+	if len(tools) != 2 {
+		t.Fatalf("got %d tools, want 2", len(tools))
+	}
+	helper.setToolTimeouts("server1", timeouts)
+	helper.replaceBackendTools("server1", tools)
+
+	if timeout, ok := helper.GetToolTimeout("server1-long_job"); !ok || timeout != 5*time.Minute {
+		t.Errorf("GetToolTimeout(server1-long_job) = %s, %v, want 5m0s", timeout, ok)
+	}
+	if _, ok := helper.GetToolTimeout("server1-echo"); ok {
+		t.Error("server1-echo has a timeout without declaring one")
+	}
+
+	// helper_tool_backend reports the timeout each call gets
+	helper.toolCallPolicies = extProc.NewServer(false, helper, extProc.Config{
+		BackendResponseTimeouts: map[string]time.Duration{"server1": 30 * time.Second},
+	})
+	request := mcp.CallToolRequest{}
+	result, err := helper.handleToolBackend(context.Background(), request)
+	if err != nil || result.IsError {
+		t.Fatalf("handleToolBackend() = %v, %v", result, err)
+	}
+	structured := result.StructuredContent.(map[string]any)
+	want := map[string]string{"server1-long_job": "5m0s", "server1-echo": "30s"}
+	if got := structured["timeouts"].(map[string]string); fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("timeouts = %v, want %v", got, want)
+	}
+}
+
+func TestToolsListChangedIsDebouncedPerBackend(t *testing.T) {
+	backend, lists := annotatedBackend(t)
+	helper := newTestHelper(t, testBackendConfig("server1", backend.URL))
+	helper.timeouts.ToolsChanged = 50 * time.Millisecond
+	if _, err := helper.refreshBackend(context.Background(), "server1"); err != nil {
+		t.Fatalf("refreshBackend() error = %v", err)
+	}
+	lists.Store(0)
+
+	sessions := []*recordingSession{newRecordingSession("client-1"), newRecordingSession("client-2")}
+	for _, session := range sessions {
+		if err := helper.mcpServer.RegisterSession(context.Background(), session); err != nil {
+			t.Fatalf("RegisterSession() error = %v", err)
+		}
+	}
+
+	// The backend's tools are unchanged, so every session that heard the notification gets it forwarded once
+	helper.handleBackendToolsChanged("server1", "client-1")
+	helper.handleBackendToolsChanged("server1", "client-2")
+	helper.handleBackendToolsChanged("server1", "client-1")
+	time.Sleep(300 * time.Millisecond)
+
+	if got := lists.Load(); got != 1 {
+		t.Errorf("backend was listed %d times, want once", got)
+	}
+	for _, session := range sessions {
+		if got := session.drain(); got != 1 {
+			t.Errorf("%s got %d notifications, want 1", session.id, got)
+		}
+	}
+}
+
+// newSlowListingBackend starts a backend serving echo whose tools/list requests are each slowed down,
+// returning its URL and the most tools/list requests it saw in flight at once
+func newSlowListingBackend(t *testing.T) (string, *atomic.Int32) {
+	t.Helper()
+
+	var inFlight, maxInFlight atomic.Int32
+	hooks := &server.Hooks{}
+	hooks.AddBeforeListTools(func(ctx context.Context, id any, message *mcp.ListToolsRequest) {
+		current := inFlight.Add(1)
+		for {
+			seen := maxInFlight.Load()
+			if current <= seen || maxInFlight.CompareAndSwap(seen, current) {
+				break
+			}
+		}
+		time.Sleep(20 * time.Millisecond)
+	})
+	hooks.AddAfterListTools(func(ctx context.Context, id any, message *mcp.ListToolsRequest, result *mcp.ListToolsResult) {
+		inFlight.Add(-1)
+	})
+	mcpServer := server.NewMCPServer("test-backend", "1.0.0", server.WithToolCapabilities(true), server.WithHooks(hooks))
+	mcpServer.AddTools(testTool("echo"))
+	backend := server.NewTestStreamableHTTPServer(mcpServer)
+	t.Cleanup(backend.Close)
+	return backend.URL, &maxInFlight
+}
+
+func TestToolSetWritersDoNotOverlap(t *testing.T) {
+	// Overlapping writers would show up as concurrent tools/list requests
+	backendURL, maxInFlight := newSlowListingBackend(t)
+
+	helper := newTestHelper(t, testBackendConfig("server1", backendURL))
+	if err := helper.initializeStartupClients(); err != nil {
+		t.Fatalf("initializeStartupClients() error = %v", err)
+	}
+	t.Cleanup(func() { helper.closeStartupClients() })
+
+	var wg sync.WaitGroup
+	for range 3 {
+		wg.Add(3)
+		go func() {
+			defer wg.Done()
+			if err := helper.aggregateTools(); err != nil {
+				t.Errorf("aggregateTools() error = %v", err)
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			if _, err := helper.RefreshTools(context.Background()); err != nil {
+				t.Errorf("RefreshTools() error = %v", err)
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			if _, err := helper.refreshBackend(context.Background(), "server1"); err != nil {
+				t.Errorf("refreshBackend() error = %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if got := maxInFlight.Load(); got != 1 {
+		t.Errorf("%d tool set writers listed tools at once, want 1", got)
+	}
+	helper.toolsLock.RLock()
+	defer helper.toolsLock.RUnlock()
+	if len(helper.aggregatedTools) != 1 || helper.toolBackends["server1-echo"] != "server1" {
+		t.Errorf("aggregated tools = %v, owners = %v, want server1-echo alone", helper.aggregatedTools, helper.toolBackends)
+	}
+}
+
+func TestRefreshHonoursDiscoveryConcurrency(t *testing.T) {
+	backendURL, maxInFlight := newSlowListingBackend(t)
+	concurrency := discoveryConcurrency
+	discoveryConcurrency = 2
+	t.Cleanup(func() { discoveryConcurrency = concurrency })
+
+	var backends []BackendConfig
+	for i := range 5 {
+		backends = append(backends, testBackendConfig(fmt.Sprintf("server%d", i+1), backendURL))
+	}
+	helper := newTestHelper(t, backends...)
+	if _, err := helper.RefreshTools(context.Background()); err != nil {
+		t.Fatalf("RefreshTools() error = %v", err)
+	}
+
+	if got := maxInFlight.Load(); got > 2 {
+		t.Errorf("refresh listed tools on %d backends at once, want at most DISCOVERY_CONCURRENCY (2)", got)
+	}
+	helper.toolsLock.RLock()
+	defer helper.toolsLock.RUnlock()
+	if len(helper.aggregatedTools) != len(backends) {
+		t.Errorf("refreshed %d tools, want one per backend (%d)", len(helper.aggregatedTools), len(backends))
+	}
+}
+
+func TestAdminRefreshOfOneBackend(t *testing.T) {
+	backend := newTestBackend(t, testTool("echo"))
+	// server2 is unreachable, so refreshing everything would fail
+	helper := newTestHelper(t, testBackendConfig("server1", backend.URL), testBackendConfig("server2", "http://127.0.0.1:1"))
+	mux := http.NewServeMux()
+	registerAdminRoutes(mux, helper, &bearerAuthenticator{token: "admin-secret"})
+
+	refresh := func(backend string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/admin/refresh?backend="+backend, nil)
+		req.Header.Set("Authorization", "Bearer admin-secret")
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, req)
+		return rec
+	}
+
+	rec := refresh("server1")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("refresh of server1 returned %d: %s", rec.Code, rec.Body)
+	}
+	var response struct {
+		Changed []toolDelta `json:"changed"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &response); err != nil {
+		t.Fatalf("decoding response: %v", err)
+	}
+	if len(response.Changed) != 1 || response.Changed[0].Backend != "server1" || len(response.Changed[0].Added) != 1 {
+		t.Errorf("changed = %+v, want server1-echo added on server1 only", response.Changed)
+	}
+
+	if rec := refresh("server3"); rec.Code != http.StatusNotFound {
+		t.Errorf("refresh of an unknown backend returned %d, want 404", rec.Code)
+	}
+}
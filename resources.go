@@ -0,0 +1,159 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+
+	extProc "mcp-helper/ext-proc"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// aggregateResources lists resources from every backend advertising the resources capability and
+// registers them with mcpServer under URIs prefixed per backend. Backends without resources, or whose
+// listing fails, simply contribute none.
+func (g *MCPHelper) aggregateResources() {
+	budgetCtx, cancel := context.WithTimeout(context.Background(), g.timeouts.DiscoveryBudget)
+	defer cancel()
+
+	var allResources []mcp.Resource
+	for _, backend := range g.backends {
+		backendClient := g.startupClients[backend.Name]
+		if backendClient == nil {
+			continue
+		}
+		if backendClient.GetServerCapabilities().Resources == nil {
+			log.Printf("%s doesn't support resources, skipping", backend.Name)
+			continue
+		}
+
+		ctx, cancel := context.WithTimeout(budgetCtx, g.timeouts.Discovery)
+		result, err := backendClient.ListResources(ctx, mcp.ListResourcesRequest{})
+		cancel()
+		if err != nil {
+			log.Printf("⚠️ Failed to list resources from %s, continuing without them: %v", backend.Name, err)
+			continue
+		}
+
+		prefix := extProc.ResourcePrefix(backend.Name)
+		for _, resource := range result.Resources {
+			resource.URI = prefix + resource.URI
+			allResources = append(allResources, resource)
+		}
+		log.Printf("%s contributed %d resources", backend.Name, len(result.Resources))
+	}
+
+	g.registerAggregatedResources(allResources)
+}
+
+// registerAggregatedResources syncs the MCP server with the aggregated resources, as
+// registerAggregatedTools does for tools, so unchanged resources don't notify clients again
+func (g *MCPHelper) registerAggregatedResources(resources []mcp.Resource) {
+	g.toolsLock.Lock()
+
+	var register []server.ServerResource
+	current := make(map[string]bool, len(resources))
+	for _, resource := range resources {
+		current[resource.URI] = true
+		hash := definitionHash(resource.URI, resource)
+		if g.resourceHashes[resource.URI] == hash {
+			continue
+		}
+		g.resourceHashes[resource.URI] = hash
+		register = append(register, server.ServerResource{
+			Resource: resource,
+			Handler:  g.routeResourceRead,
+		})
+	}
+
+	var remove []string
+	for uri := range g.resourceHashes {
+		if !current[uri] {
+			remove = append(remove, uri)
+			delete(g.resourceHashes, uri)
+		}
+	}
+	g.toolsLock.Unlock()
+
+	// Registrations notify sessions with resources/list_changed, so they are made outside the lock
+	for _, uri := range remove {
+		g.mcpServer.RemoveResource(uri)
+	}
+	if len(register) > 0 {
+		g.mcpServer.AddResources(register...)
+	}
+
+	log.Printf("Registered %d aggregated resources with MCP server (%d added or changed, %d removed)",
+		len(resources), len(register), len(remove))
+}
+
+// definitionHash is a hash of a resource or prompt definition, stable because the mcp-go types
+// marshal their fields in a fixed order
+func definitionHash(name string, definition any) string {
+	data, err := json.Marshal(definition)
+	if err != nil {
+		log.Printf("⚠️ Failed to hash %s: %v", name, err)
+		return ""
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// routeResourceRead handles resources/read requests that reach the helper. ext-proc routes reads of
+// backend resources straight to the backend, so only standalone mode serves them here.
+func (g *MCPHelper) routeResourceRead(ctx context.Context, req mcp.ReadResourceRequest) ([]mcp.ResourceContents, error) {
+	uri := req.Params.URI
+
+	target := req.Header.Get("x-mcp-server")
+	if target == "" {
+		if standaloneMode {
+			return g.forwardResourceRead(ctx, req)
+		}
+		log.Printf("❌ Resource read %s reached helper without Envoy routing", uri)
+		return nil, fmt.Errorf("resource %s can't be read: this helper was reached directly rather than through the Envoy gateway", uri)
+	}
+
+	log.Printf("❌ Resource read %s was routed to %s but reached helper, check the Envoy route for x-mcp-server=%s", uri, target, target)
+	return nil, fmt.Errorf("resource %s can't be read: the gateway has no route to backend %s", uri, target)
+}
+
+// forwardResourceRead reads a backend resource over the session's own backend connection, for standalone mode
+func (g *MCPHelper) forwardResourceRead(ctx context.Context, req mcp.ReadResourceRequest) ([]mcp.ResourceContents, error) {
+	uri := req.Params.URI
+
+	session := server.ClientSessionFromContext(ctx)
+	if session == nil {
+		return nil, fmt.Errorf("resource %s can't be forwarded without a client session", uri)
+	}
+
+	g.connectionsLock.RLock()
+	connections, exists := g.clientConnections[session.SessionID()]
+	g.connectionsLock.RUnlock()
+	if !exists {
+		return nil, fmt.Errorf("resource %s can't be forwarded, no backend connections for this session yet", uri)
+	}
+
+	target, backendURI, found := extProc.StripResourcePrefix(uri)
+	if !found {
+		return nil, fmt.Errorf("resource %s doesn't belong to any backend", uri)
+	}
+	backendClient := connections.Clients[target]
+	if backendClient == nil {
+		return nil, fmt.Errorf("resource %s can't be forwarded, %s is not connected", uri, target)
+	}
+
+	log.Printf("➡️ Standalone mode: forwarding read of %s to %s as %s", uri, target, backendURI)
+	backendReq := mcp.ReadResourceRequest{}
+	backendReq.Params.URI = backendURI
+	backendReq.Params.Arguments = req.Params.Arguments
+	result, err := backendClient.ReadResource(ctx, backendReq)
+	if err != nil {
+		return nil, err
+	}
+	return result.Contents, nil
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"regexp"
+	"strconv"
+	"time"
+
+	"github.com/mark3labs/mcp-go/client"
+	"github.com/mark3labs/mcp-go/client/transport"
+	"github.com/mark3labs/mcp-go/mcp"
+
+	extProc "mcp-helper/ext-proc"
+)
+
+// Retries of a backend initialize that failed transiently: attempts in total (1 disables retries),
+// and the exponential backoff between them, doubling from the base delay up to the max delay
+var (
+	backendInitAttempts      = getEnvInt("BACKEND_INIT_ATTEMPTS", 3)
+	backendInitRetryDelay    = getEnvDuration("BACKEND_INIT_RETRY_DELAY", 200*time.Millisecond)
+	backendInitRetryMaxDelay = getEnvDuration("BACKEND_INIT_RETRY_MAX_DELAY", 2*time.Second)
+
+	// Retries per second each backend may receive across all sessions, and how many may burst,
+	// so a struggling backend isn't hit by every session's retries at once; 0 disables the budget
+	backendRetryBudget      = getEnvFloat("BACKEND_RETRY_BUDGET", 5)
+	backendRetryBudgetBurst = getEnvInt("BACKEND_RETRY_BUDGET_BURST", 10)
+)
+
+// httpStatusPattern finds the HTTP status in the transport's "request failed with status <code>" errors
+var httpStatusPattern = regexp.MustCompile(`status (\d{3})`)
+
+// dialBackendWithRetry dials a backend for a client session, retrying transient failures with
+// exponential backoff until the attempts run out, ctx is done or the backend's retry budget is spent
+func (g *MCPHelper) dialBackendWithRetry(ctx context.Context, clientSessionID string, backend BackendConfig) (*client.Client, *mcp.InitializeResult, error) {
+	for attempt := 1; ; attempt++ {
+		mcpClient, serverInfo, err := g.dialBackend(ctx, clientSessionID, backend)
+		if err == nil {
+			return mcpClient, serverInfo, nil
+		}
+		if attempt >= backendInitAttempts || !isTransientInitError(err) {
+			return nil, nil, err
+		}
+		if g.retryBudget != nil && !g.retryBudget.Allow(backend.Name) {
+			retryBudgetExhaustedTotal.WithLabelValues(backend.Name).Inc()
+			log.Printf("🔁 Retry budget for %s exhausted, not retrying initialize for client %s", backend.Name, clientSessionID)
+			return nil, nil, fmt.Errorf("%w (retry budget exhausted)", err)
+		}
+
+		delay := backoffDelay(attempt, backendInitRetryDelay, backendInitRetryMaxDelay)
+		log.Printf("🔁 Initialize of %s for client %s failed (attempt %d/%d), retrying in %s: %v",
+			backend.Name, clientSessionID, attempt, backendInitAttempts, delay, err)
+		select {
+		case <-ctx.Done():
+			return nil, nil, fmt.Errorf("%w (gave up retrying: %v)", err, ctx.Err())
+		case <-time.After(delay):
+		}
+	}
+}
+
+// newRetryBudget creates the per-backend retry budget, or nil when disabled
+func newRetryBudget() *extProc.RateLimiter {
+	if backendRetryBudget <= 0 {
+		return nil
+	}
+	return extProc.NewRateLimiter(backendRetryBudget, backendRetryBudgetBurst)
+}
+
+// isTransientInitError reports whether an initialize failure is worth retrying: the backend couldn't
+// be reached, timed out, or answered 429 or 5xx. A backend that answered at the protocol level, with a
+// JSON-RPC error or an unsupported protocol version, fails immediately.
+func isTransientInitError(err error) bool {
+	var transportErr *transport.Error
+	if !errors.As(err, &transportErr) {
+		return false
+	}
+	if match := httpStatusPattern.FindStringSubmatch(transportErr.Error()); match != nil {
+		status, _ := strconv.Atoi(match[1])
+		return status == 429 || status >= 500
+	}
+	return true
+}
+
+// backoffDelay returns the delay before retry number attempt, doubling from base and capped at maxDelay
+func backoffDelay(attempt int, base, maxDelay time.Duration) time.Duration {
+	delay := base
+	for i := 1; i < attempt && delay < maxDelay; i++ {
+		delay *= 2
+	}
+	return min(delay, maxDelay)
+}
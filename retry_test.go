@@ -0,0 +1,54 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+
+	extProc "mcp-helper/ext-proc"
+)
+
+func TestRetryBudgetFailsFast(t *testing.T) {
+	var hits atomic.Int32
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+		http.Error(w, "overloaded", http.StatusServiceUnavailable)
+	}))
+	defer backend.Close()
+
+	attempts, delay := backendInitAttempts, backendInitRetryDelay
+	backendInitAttempts, backendInitRetryDelay = 5, time.Millisecond
+	t.Cleanup(func() { backendInitAttempts, backendInitRetryDelay = attempts, delay })
+
+	helper := newTestHelper(t, testBackendConfig("server1", backend.URL))
+	// One retry for the backend, refilling too slowly to matter here
+	helper.retryBudget = extProc.NewRateLimiter(0.001, 1)
+	exhausted := retryBudgetExhaustedTotal.WithLabelValues("server1")
+	before := testutil.ToFloat64(exhausted)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if _, _, err := helper.dialBackendWithRetry(ctx, "client-1", helper.backends[0]); err == nil {
+		t.Fatal("dialBackendWithRetry() succeeded against a failing backend")
+	}
+	if got := hits.Load(); got != 2 {
+		t.Errorf("backend saw %d initializes, want 2 (the first attempt and one budgeted retry)", got)
+	}
+
+	// Another session's dial gets no retry at all
+	hits.Store(0)
+	if _, _, err := helper.dialBackendWithRetry(ctx, "client-2", helper.backends[0]); err == nil {
+		t.Fatal("dialBackendWithRetry() succeeded against a failing backend")
+	}
+	if got := hits.Load(); got != 1 {
+		t.Errorf("backend saw %d initializes once the budget was spent, want 1", got)
+	}
+	if got := testutil.ToFloat64(exhausted) - before; got != 2 {
+		t.Errorf("exhausted counter grew by %v, want 2", got)
+	}
+}
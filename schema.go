@@ -0,0 +1,75 @@
+package main
+
+import (
+	"encoding/json"
+	"log"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// Largest input schema served in tools/list before it is summarized, 0 serves schemas in full
+var maxToolSchemaBytes = getEnvInt("MAX_TOOL_SCHEMA_BYTES", 64*1024)
+
+// Property descriptions are cut to this length when a schema is summarized
+const maxSummarizedDescription = 200
+
+// limitToolSchema shrinks an oversized input schema so tools/list stays cheap to serialize.
+// Properties are first reduced to their type and description, then dropped entirely;
+// required arguments are always kept so validation keeps working.
+func limitToolSchema(tool mcp.Tool, maxBytes int) mcp.Tool {
+	if maxBytes <= 0 {
+		return tool
+	}
+
+	size := schemaSize(tool.InputSchema)
+	if size <= maxBytes {
+		return tool
+	}
+
+	summarized := tool.InputSchema
+	summarized.Defs = nil
+	summarized.Properties = make(map[string]any, len(tool.InputSchema.Properties))
+	for name, property := range tool.InputSchema.Properties {
+		summarized.Properties[name] = summarizeProperty(property)
+	}
+
+	if summarizedSize := schemaSize(summarized); summarizedSize <= maxBytes {
+		log.Printf("✂️ Summarized input schema of %s from %d to %d bytes (limit %d)", tool.Name, size, summarizedSize, maxBytes)
+		tool.InputSchema = summarized
+		return tool
+	}
+
+	summarized.Properties = nil
+	log.Printf("✂️ Dropped input schema properties of %s, %d bytes exceeds limit %d even when summarized", tool.Name, size, maxBytes)
+	tool.InputSchema = summarized
+	return tool
+}
+
+// summarizeProperty keeps only the type and a shortened description of a schema property
+func summarizeProperty(property any) any {
+	fields, ok := property.(map[string]any)
+	if !ok {
+		return property
+	}
+
+	summary := make(map[string]any, 2)
+	if propertyType, exists := fields["type"]; exists {
+		summary["type"] = propertyType
+	}
+	if description, ok := fields["description"].(string); ok {
+		if runes := []rune(description); len(runes) > maxSummarizedDescription {
+			description = string(runes[:maxSummarizedDescription]) + "..."
+		}
+		summary["description"] = description
+	}
+	return summary
+}
+
+// schemaSize returns the serialized size of an input schema
+func schemaSize(schema mcp.ToolInputSchema) int {
+	data, err := json.Marshal(schema)
+	if err != nil {
+		return 0
+	}
+	return len(data)
+}
@@ -0,0 +1,190 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"time"
+
+	extProc "mcp-helper/ext-proc"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// runSelfTest opens a session through the helper's MCP handler, then routes a synthetic tool call for each
+// backend through the ext-proc request and response handlers, checking the prefix strip, backend session
+// injection and session reverse mapping. Every step uses the configured client session header, so a
+// custom SESSION_HEADER is checked end to end. It prints a pass/fail report and returns the process exit code.
+func (g *MCPHelper) runSelfTest(processor *extProc.Server, handler http.Handler) int {
+	log.Println("🧪 Running routing self-test...")
+	defer func() {
+		if err := g.Close(); err != nil {
+			log.Printf("⚠️ Self-test cleanup failed: %v", err)
+		}
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), g.sessionInitTimeout(g.timeouts.Init))
+	defer cancel()
+
+	helperSession, err := g.selfTestSession(ctx, handler)
+	if err != nil {
+		log.Printf("❌ Self-test FAILED: could not open a session on %s: %v", extProc.ClientSessionHeader(), err)
+		return 1
+	}
+	log.Printf("✅ Session %s opened on %s", helperSession, extProc.ClientSessionHeader())
+	mapping, _ := g.GetSessionMapping(helperSession)
+
+	failed := 0
+	for _, backend := range g.backends {
+		if problems := g.selfTestBackend(ctx, processor, helperSession, backend.Name, mapping.BackendSessions[backend.Name]); len(problems) > 0 {
+			failed++
+			log.Printf("❌ %s: FAIL", backend.Name)
+			for _, problem := range problems {
+				log.Printf("   - %s", problem)
+			}
+		} else {
+			log.Printf("✅ %s: PASS", backend.Name)
+		}
+	}
+
+	if failed > 0 {
+		log.Printf("❌ Self-test FAILED for %d backend(s)", failed)
+		return 1
+	}
+	log.Println("✅ Self-test passed")
+	return 0
+}
+
+// selfTestSession initializes a session through the helper's MCP handler as a client behind the gateway
+// would, waits for the helper to map it to backend sessions, and checks a follow-up request on the
+// client session header is accepted. It returns the helper session ID.
+func (g *MCPHelper) selfTestSession(ctx context.Context, handler http.Handler) (string, error) {
+	header := extProc.ClientSessionHeader()
+
+	initialize := mcp.InitializeRequest{}
+	initialize.Params.ProtocolVersion = mcp.LATEST_PROTOCOL_VERSION
+	initialize.Params.ClientInfo = mcp.Implementation{Name: "MCP Helper (Self-test)", Version: "1.0.0"}
+	response := selfTestPost(ctx, handler, "", map[string]any{
+		"jsonrpc": "2.0",
+		"id":      1,
+		"method":  string(mcp.MethodInitialize),
+		"params":  initialize.Params,
+	})
+	if response.Code != http.StatusOK {
+		return "", fmt.Errorf("initialize returned %d: %s", response.Code, strings.TrimSpace(response.Body.String()))
+	}
+	helperSession := response.Header().Get(header)
+	if helperSession == "" {
+		return "", fmt.Errorf("initialize response has no %s header", header)
+	}
+	if !strings.EqualFold(header, server.HeaderKeySessionID) && response.Header().Get(server.HeaderKeySessionID) != "" {
+		return "", fmt.Errorf("initialize response also carries %s", server.HeaderKeySessionID)
+	}
+
+	// The helper captures the session from the response and maps it to backend sessions in the background
+	for {
+		if _, found := g.GetSessionMapping(helperSession); found {
+			break
+		}
+		select {
+		case <-ctx.Done():
+			return "", fmt.Errorf("no backend sessions were mapped to %s: %w", helperSession, ctx.Err())
+		case <-time.After(50 * time.Millisecond):
+		}
+	}
+
+	response = selfTestPost(ctx, handler, helperSession, map[string]any{
+		"jsonrpc": "2.0",
+		"id":      2,
+		"method":  string(mcp.MethodPing),
+	})
+	if response.Code != http.StatusOK {
+		return "", fmt.Errorf("request on %s returned %d: %s", header, response.Code, strings.TrimSpace(response.Body.String()))
+	}
+	return helperSession, nil
+}
+
+// selfTestPost sends a JSON-RPC message to the MCP handler, carrying the helper session on the client session header
+func selfTestPost(ctx context.Context, handler http.Handler, helperSession string, message map[string]any) *httptest.ResponseRecorder {
+	body, _ := json.Marshal(message)
+	request := httptest.NewRequest(http.MethodPost, "/mcp", strings.NewReader(string(body))).WithContext(ctx)
+	request.Header.Set("Content-Type", "application/json")
+	request.Header.Set("Accept", "application/json, text/event-stream")
+	if helperSession != "" {
+		request.Header.Set(extProc.ClientSessionHeader(), helperSession)
+	}
+
+	response := httptest.NewRecorder()
+	handler.ServeHTTP(response, request)
+	return response
+}
+
+// selfTestBackend routes one synthetic call to a backend and returns everything that didn't match expectations
+func (g *MCPHelper) selfTestBackend(ctx context.Context, processor *extProc.Server, helperSession, backend, backendSession string) []string {
+	tool, ok := g.selfTestTool(backend)
+	if !ok {
+		return []string{"no aggregated tools to route"}
+	}
+	log.Printf("🧪 %s: routing %s", backend, tool.Name)
+
+	// Placeholder values keep the call past required-argument validation; it is never sent to the backend
+	arguments := make(map[string]any)
+	for _, name := range tool.InputSchema.Required {
+		arguments[name] = ""
+	}
+
+	result, err := processor.SelfTestRoute(ctx, helperSession, tool.Name, arguments)
+	if err != nil {
+		return []string{err.Error()}
+	}
+	if result.Rejected != "" {
+		return []string{"call was not routed: " + result.Rejected}
+	}
+
+	var problems []string
+	if result.Target != backend {
+		problems = append(problems, fmt.Sprintf("routed to %q, expected %q", result.Target, backend))
+	}
+	if expected := strings.TrimPrefix(tool.Name, extProc.ToolPrefix(backend)); result.Tool != expected {
+		problems = append(problems, fmt.Sprintf("backend sees tool %q, expected %q", result.Tool, expected))
+	}
+	if result.BackendSession != backendSession {
+		problems = append(problems, fmt.Sprintf("%s carries session %q, expected %q",
+			result.SessionHeader, result.BackendSession, backendSession))
+	}
+	if result.ClientSession != helperSession {
+		problems = append(problems, fmt.Sprintf("response %s mapped to %q, expected %q",
+			extProc.ClientSessionHeader(), result.ClientSession, helperSession))
+	}
+	return problems
+}
+
+// selfTestTool picks the tool to route for a backend, preferring read-only tools without required arguments
+func (g *MCPHelper) selfTestTool(backend string) (mcp.Tool, bool) {
+	g.toolsLock.RLock()
+	defer g.toolsLock.RUnlock()
+
+	var best mcp.Tool
+	bestScore := -1
+	for _, tool := range g.aggregatedTools {
+		if g.toolBackends[tool.Name] != backend {
+			continue
+		}
+		score := 0
+		if tool.Annotations.ReadOnlyHint != nil && *tool.Annotations.ReadOnlyHint {
+			score += 2
+		}
+		if len(tool.InputSchema.Required) == 0 {
+			score++
+		}
+		if score > bestScore {
+			best, bestScore = tool, score
+		}
+	}
+	return best, bestScore >= 0
+}
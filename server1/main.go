@@ -3,11 +3,14 @@ package main
 import (
 	"bytes"
 	"context"
+	"encoding/json"
 	"flag"
 	"fmt"
 	"io"
 	"log"
+	"log/slog"
 	"net/http"
+	"os"
 	"time"
 
 	"github.com/mark3labs/mcp-go/mcp"
@@ -16,19 +19,34 @@ import (
 
 func main() {
 	var port = flag.String("port", "8081", "Port to listen on")
+	var toolsFile = flag.String("tools-file", "", "Load tool definitions from a JSON file instead of the built-in tools")
+	var logLevel = flag.String("log-level", "info", "Log level: debug adds request header and body dumps")
 	flag.Parse()
 
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(*logLevel)); err != nil {
+		log.Fatalf("Invalid -log-level: %v", err)
+	}
+	slog.SetLogLoggerLevel(level)
+
 	log.Println("Starting MCP Test Server 1...")
 
-	// Create MCP server instance with only tool capabilities
+	// Create MCP server instance with tool and logging capabilities
 	mcpServer := server.NewMCPServer(
 		"Test Server 1",
 		"1.0.0",
 		server.WithToolCapabilities(true),
+		server.WithLogging(),
 	)
 
-	// Setup the two tools
-	setupTools(mcpServer)
+	// Setup the built-in tools, or the tools from the fixture file when given
+	if *toolsFile != "" {
+		if err := setupToolsFromFile(mcpServer, *toolsFile); err != nil {
+			log.Fatalf("Failed to load tools from %s: %v", *toolsFile, err)
+		}
+	} else {
+		setupTools(mcpServer)
+	}
 
 	// Create streamable HTTP server and start it
 	log.Printf("Test Server 1 listening on port %s", *port)
@@ -48,13 +66,16 @@ func main() {
 // loggingMiddleware adds comprehensive logging for all HTTP requests
 func loggingMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		// Log all headers for debugging
-		log.Printf("=== SERVER1 REQUEST ===")
-		log.Printf("Method: %s, URL: %s", r.Method, r.URL.String())
-		log.Printf("Headers:")
-		for name, values := range r.Header {
-			for _, value := range values {
-				log.Printf("  %s: %s", name, value)
+		// Dump all headers only at debug level
+		debug := slog.Default().Enabled(r.Context(), slog.LevelDebug)
+		if debug {
+			log.Printf("=== SERVER1 REQUEST ===")
+			log.Printf("Method: %s, URL: %s", r.Method, r.URL.String())
+			log.Printf("Headers:")
+			for name, values := range r.Header {
+				for _, value := range values {
+					log.Printf("  %s: %s", name, value)
+				}
 			}
 		}
 
@@ -72,17 +93,24 @@ func loggingMiddleware(next http.Handler) http.Handler {
 			if err != nil {
 				log.Printf("❌ [SERVER1] Error reading request body: %v", err)
 			} else if len(bodyBytes) > 0 {
-				log.Printf("📝 [SERVER1] Request Body (%d bytes):", len(bodyBytes))
-				log.Printf("%s", string(bodyBytes))
+				if debug {
+					log.Printf("📝 [SERVER1] Request Body (%d bytes):", len(bodyBytes))
+					log.Printf("%s", string(bodyBytes))
+				}
 
 				// Restore the body for the actual handler to read
 				r.Body = io.NopCloser(bytes.NewBuffer(bodyBytes))
-			} else {
+
+				// Keep the raw body for the echo_request tool
+				r = r.WithContext(context.WithValue(r.Context(), "http_body", bodyBytes))
+			} else if debug {
 				log.Printf("📝 [SERVER1] Request Body: (empty)")
 			}
 		}
 
-		log.Printf("=======================")
+		if debug {
+			log.Printf("=======================")
+		}
 
 		// Add HTTP headers to context for tool handlers to access
 		ctx := context.WithValue(r.Context(), "http_headers", map[string][]string(r.Header))
@@ -97,6 +125,7 @@ func setupTools(s *server.MCPServer) {
 	// Echo tool - echoes back the input string
 	s.AddTool(mcp.NewTool("echo",
 		mcp.WithDescription("Echoes back the input message"),
+		mcp.WithReadOnlyHintAnnotation(true),
 		mcp.WithString("message",
 			mcp.Description("Message to echo back"),
 			mcp.Required(),
@@ -112,6 +141,11 @@ func setupTools(s *server.MCPServer) {
 	s.AddTool(mcp.NewTool("echo_headers",
 		mcp.WithDescription("Returns all headers received by the server"),
 	), handleEchoHeaders)
+
+	// Echo request tool - returns the full JSON-RPC request as received by the server
+	s.AddTool(mcp.NewTool("echo_request",
+		mcp.WithDescription("Returns the full JSON-RPC request and session ID received by the server"),
+	), handleEchoRequest)
 }
 
 // handleEcho handles the echo tool
@@ -165,3 +199,95 @@ func handleEchoHeaders(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallT
 	log.Printf("✅ [SERVER1] EchoHeaders returning headers")
 	return mcp.NewToolResultText(result), nil
 }
+
+// fileTool is a tool definition loaded from a -tools-file fixture
+type fileTool struct {
+	Name        string             `json:"name"`
+	Description string             `json:"description"`
+	InputSchema json.RawMessage    `json:"inputSchema"`
+	Annotations mcp.ToolAnnotation `json:"annotations"`
+}
+
+// setupToolsFromFile registers tools from a JSON array of tool definitions.
+// Every tool responds with the arguments it was called with.
+func setupToolsFromFile(s *server.MCPServer, path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read tools file: %w", err)
+	}
+
+	var tools []fileTool
+	if err := json.Unmarshal(data, &tools); err != nil {
+		return fmt.Errorf("failed to parse tools file: %w", err)
+	}
+
+	for _, def := range tools {
+		if def.Name == "" {
+			return fmt.Errorf("tool definition without a name in %s", path)
+		}
+		schema := def.InputSchema
+		if len(schema) == 0 {
+			schema = json.RawMessage(`{"type":"object"}`)
+		}
+
+		tool := mcp.NewToolWithRawSchema(def.Name, def.Description, schema)
+		tool.Annotations = def.Annotations
+		s.AddTool(tool, handleFileTool)
+	}
+
+	log.Printf("✅ [SERVER1] Registered %d tools from %s", len(tools), path)
+	return nil
+}
+
+// handleFileTool handles tools loaded from a -tools-file fixture
+func handleFileTool(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	log.Printf("🔧 [SERVER1] handleFileTool called for %s", req.Params.Name)
+
+	result, err := json.MarshalIndent(map[string]interface{}{
+		"server":    "server1",
+		"tool":      req.Params.Name,
+		"arguments": req.GetArguments(),
+	}, "", "  ")
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to encode result: %v", err)), nil
+	}
+
+	return mcp.NewToolResultText(string(result)), nil
+}
+
+// handleEchoRequest handles the echo_request tool
+func handleEchoRequest(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	log.Printf("🔧 [SERVER1] handleEchoRequest called")
+
+	request := make(map[string]interface{})
+	request["server"] = "server1"
+	request["method"] = req.Method
+	request["params"] = req.Params
+
+	// Session ID as resolved by the MCP server for this request
+	if session := server.ClientSessionFromContext(ctx); session != nil {
+		request["session_id"] = session.SessionID()
+	}
+
+	// Include the raw body so the ext-proc rewrite can be verified exactly as it arrived
+	if body, ok := ctx.Value("http_body").([]byte); ok {
+		var parsed map[string]interface{}
+		if err := json.Unmarshal(body, &parsed); err != nil {
+			request["raw_body"] = string(body)
+			request["body_error"] = err.Error()
+		} else {
+			request["body"] = parsed
+		}
+	} else {
+		request["context_debug"] = "No request body found in context"
+	}
+
+	result, err := json.MarshalIndent(request, "", "  ")
+	if err != nil {
+		log.Printf("❌ [SERVER1] EchoRequest error: %v", err)
+		return mcp.NewToolResultError(fmt.Sprintf("Failed to encode request: %v", err)), nil
+	}
+
+	log.Printf("✅ [SERVER1] EchoRequest returning request")
+	return mcp.NewToolResultText(string(result)), nil
+}
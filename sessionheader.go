@@ -0,0 +1,68 @@
+package main
+
+import (
+	"net/http"
+	"strings"
+
+	extProc "mcp-helper/ext-proc"
+
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// Header clients and the gateway use to carry the helper session; the MCP server behind the
+// helper always uses the standard header, so the middleware translates between the two
+var clientSessionHeader = getEnv("SESSION_HEADER", server.HeaderKeySessionID)
+
+// sessionHeaderMiddleware renames the client session header to the standard MCP session header on
+// requests and back on responses, when a gateway uses its own session header
+func sessionHeaderMiddleware(next http.Handler) http.Handler {
+	header := extProc.ClientSessionHeader()
+	if strings.EqualFold(header, server.HeaderKeySessionID) {
+		return next
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if values := r.Header.Values(header); len(values) > 0 {
+			r.Header.Del(header)
+			r.Header[server.HeaderKeySessionID] = values
+		}
+		next.ServeHTTP(&sessionHeaderWriter{ResponseWriter: w, header: header}, r)
+	})
+}
+
+// sessionHeaderWriter moves the standard session header to the client session header before the
+// response headers are sent
+type sessionHeaderWriter struct {
+	http.ResponseWriter
+	header      string
+	wroteHeader bool
+}
+
+func (w *sessionHeaderWriter) renameSessionHeader() {
+	if w.wroteHeader {
+		return
+	}
+	w.wroteHeader = true
+	if sessionID := w.Header().Get(server.HeaderKeySessionID); sessionID != "" {
+		w.Header().Del(server.HeaderKeySessionID)
+		w.Header().Set(w.header, sessionID)
+	}
+}
+
+func (w *sessionHeaderWriter) WriteHeader(statusCode int) {
+	w.renameSessionHeader()
+	w.ResponseWriter.WriteHeader(statusCode)
+}
+
+func (w *sessionHeaderWriter) Write(data []byte) (int, error) {
+	w.renameSessionHeader()
+	return w.ResponseWriter.Write(data)
+}
+
+// Flush passes flushes through so SSE responses still stream
+func (w *sessionHeaderWriter) Flush() {
+	w.renameSessionHeader()
+	if flusher, ok := w.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
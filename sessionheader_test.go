@@ -0,0 +1,97 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	extProc "mcp-helper/ext-proc"
+
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// useClientSessionHeader switches the helper session header for the duration of a test
+func useClientSessionHeader(t *testing.T, header string) {
+	t.Helper()
+
+	extProc.SetClientSessionHeader(header)
+	t.Cleanup(func() { extProc.SetClientSessionHeader(server.HeaderKeySessionID) })
+}
+
+// postJSONRPC posts a JSON-RPC message to the helper, carrying helperSession on header when set
+func postJSONRPC(t *testing.T, url, header, helperSession, message string) *http.Response {
+	t.Helper()
+
+	request, err := http.NewRequest(http.MethodPost, url+"/mcp", strings.NewReader(message))
+	if err != nil {
+		t.Fatalf("creating request: %v", err)
+	}
+	request.Header.Set("Content-Type", "application/json")
+	request.Header.Set("Accept", "application/json, text/event-stream")
+	if helperSession != "" {
+		request.Header.Set(header, helperSession)
+	}
+	response, err := http.DefaultClient.Do(request)
+	if err != nil {
+		t.Fatalf("posting %s: %v", message, err)
+	}
+	response.Body.Close()
+	return response
+}
+
+func TestCustomClientSessionHeader(t *testing.T) {
+	const header = "X-Gateway-Session"
+	useClientSessionHeader(t, header)
+
+	backend := newTestBackend(t, testTool("echo"))
+	config := testBackendConfig("server1", backend.URL)
+	if err := registerBackendRoutes([]BackendConfig{config}); err != nil {
+		t.Fatalf("registerBackendRoutes() error = %v", err)
+	}
+	helper := newTestHelper(t, config)
+	if err := helper.initializeBackends(); err != nil {
+		t.Fatalf("initializeBackends() error = %v", err)
+	}
+	helperServer := httptest.NewServer(helper.mcpHandler())
+	defer helperServer.Close()
+
+	response := postJSONRPC(t, helperServer.URL, header, "",
+		`{"jsonrpc":"2.0","id":1,"method":"initialize","params":{"protocolVersion":"2025-03-26","clientInfo":{"name":"test-client","version":"1.0.0"}}}`)
+	helperSession := response.Header.Get(header)
+	if response.StatusCode != http.StatusOK || helperSession == "" {
+		t.Fatalf("initialize returned %d with %s %q, want 200 and a helper session", response.StatusCode, header, helperSession)
+	}
+	if standard := response.Header.Get(server.HeaderKeySessionID); standard != "" {
+		t.Errorf("initialize response also carries %s: %s", server.HeaderKeySessionID, standard)
+	}
+	waitForSessionMapping(t, helper, helperSession)
+
+	if response := postJSONRPC(t, helperServer.URL, header, helperSession, `{"jsonrpc":"2.0","id":2,"method":"ping"}`); response.StatusCode != http.StatusOK {
+		t.Errorf("ping on %s returned %d, want 200", header, response.StatusCode)
+	}
+
+	// The gateway side reads and writes the helper session on the same header
+	mapping, _ := helper.GetSessionMapping(helperSession)
+	processor := extProc.NewServer(false, helper, extProc.Config{})
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	result, err := processor.SelfTestRoute(ctx, helperSession, "server1-echo", map[string]any{})
+	if err != nil {
+		t.Fatalf("SelfTestRoute() error = %v", err)
+	}
+	if result.Rejected != "" {
+		t.Fatalf("tool call was rejected: %s", result.Rejected)
+	}
+	if result.Target != "server1" || result.Tool != "echo" {
+		t.Errorf("routed to %s as %s, want server1 as echo", result.Target, result.Tool)
+	}
+	if result.SessionHeader != "mcp-session-id" || result.BackendSession != mapping.BackendSessions["server1"] {
+		t.Errorf("backend got %s: %q, want mcp-session-id: %q", result.SessionHeader, result.BackendSession, mapping.BackendSessions["server1"])
+	}
+	if result.ClientSession != helperSession {
+		t.Errorf("response mapped to %s %q, want %q", header, result.ClientSession, helperSession)
+	}
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"sync"
+	"time"
+
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// sessionIDManager issues helper session IDs and remembers which are live. mcp-go answers requests on
+// any other ID - evicted, deleted by the client or issued before a restart - with 404, which tells
+// MCP clients to initialize a new session instead of failing every call.
+type sessionIDManager struct {
+	server.InsecureStatefulSessionIdManager
+
+	live map[string]time.Time // helper session ID -> when it was issued
+	lock sync.Mutex
+
+	// Called when a client deletes its session, to release what the helper holds for it (nil for none)
+	onTerminate func(sessionID string)
+}
+
+func newSessionIDManager() *sessionIDManager {
+	return &sessionIDManager{live: make(map[string]time.Time)}
+}
+
+// Generate issues a new live session ID
+func (m *sessionIDManager) Generate() string {
+	sessionID := m.InsecureStatefulSessionIdManager.Generate()
+
+	m.lock.Lock()
+	m.live[sessionID] = time.Now()
+	m.lock.Unlock()
+	return sessionID
+}
+
+// Validate rejects malformed IDs and reports IDs the helper no longer knows as terminated
+func (m *sessionIDManager) Validate(sessionID string) (bool, error) {
+	if _, err := m.InsecureStatefulSessionIdManager.Validate(sessionID); err != nil {
+		return false, err
+	}
+
+	m.lock.Lock()
+	_, live := m.live[sessionID]
+	m.lock.Unlock()
+	return !live, nil
+}
+
+// Terminate ends a session at the client's request
+func (m *sessionIDManager) Terminate(sessionID string) (bool, error) {
+	m.remove(sessionID)
+	if m.onTerminate != nil {
+		m.onTerminate(sessionID)
+	}
+	return false, nil
+}
+
+// remove ends a session, so requests on its ID get 404
+func (m *sessionIDManager) remove(sessionID string) {
+	m.lock.Lock()
+	delete(m.live, sessionID)
+	m.lock.Unlock()
+}
+
+// expire ends every session issued before cutoff and returns their IDs
+func (m *sessionIDManager) expire(cutoff time.Time) []string {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	var expired []string
+	for sessionID, issuedAt := range m.live {
+		if issuedAt.Before(cutoff) {
+			delete(m.live, sessionID)
+			expired = append(expired, sessionID)
+		}
+	}
+	return expired
+}
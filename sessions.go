@@ -0,0 +1,124 @@
+package main
+
+import (
+	"encoding/json"
+	"log"
+	"maps"
+	"net/http"
+	"sort"
+	"time"
+)
+
+// adminSession is one helper session as reported by /admin/sessions
+type adminSession struct {
+	HelperSession   string                  `json:"helper_session"`
+	Principal       string                  `json:"principal,omitempty"`
+	BackendSessions map[string]string       `json:"backend_sessions"`
+	CreatedAt       time.Time               `json:"created_at"`
+	Connections     []adminConnectionHealth `json:"connections,omitempty"`
+}
+
+// adminConnectionHealth is the state of a session's connection to one backend
+type adminConnectionHealth struct {
+	Backend     string `json:"backend"`
+	Connected   bool   `json:"connected"`
+	Session     string `json:"session,omitempty"`
+	Maintenance bool   `json:"maintenance,omitempty"`
+	Unavailable string `json:"unavailable,omitempty"`
+	Unreachable string `json:"unreachable,omitempty"`
+}
+
+// handleAdminSessions lists every helper session with its backend session IDs
+func (g *MCPHelper) handleAdminSessions(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	g.sessionLock.RLock()
+	sessions := make([]adminSession, 0, len(g.sessionMappings))
+	for _, mapping := range g.sessionMappings {
+		sessions = append(sessions, adminSessionFromMapping(mapping))
+	}
+	g.sessionLock.RUnlock()
+
+	sort.Slice(sessions, func(i, j int) bool {
+		return sessions[i].HelperSession < sessions[j].HelperSession
+	})
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(map[string][]adminSession{"sessions": sessions}); err != nil {
+		log.Printf("Failed to write sessions: %v", err)
+	}
+}
+
+// handleAdminSession reports a single helper session, including the health of each of its
+// backend connections
+func (g *MCPHelper) handleAdminSession(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	id := r.PathValue("id")
+	g.sessionLock.RLock()
+	mapping, ok := g.sessionMappings[id]
+	var session adminSession
+	if ok {
+		session = adminSessionFromMapping(mapping)
+	}
+	g.sessionLock.RUnlock()
+	if !ok {
+		http.Error(w, "Unknown session: "+id, http.StatusNotFound)
+		return
+	}
+	session.Connections = g.sessionConnectionHealth(id)
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(session); err != nil {
+		log.Printf("Failed to write session %s: %v", id, err)
+	}
+}
+
+// adminSessionFromMapping copies a session mapping; the caller holds sessionLock
+func adminSessionFromMapping(mapping *SessionMapping) adminSession {
+	return adminSession{
+		HelperSession:   mapping.HelperSessionID,
+		Principal:       mapping.Principal,
+		BackendSessions: maps.Clone(mapping.BackendSessions),
+		CreatedAt:       mapping.CreatedAt,
+	}
+}
+
+// sessionConnectionHealth reports, for every configured backend, whether the session holds a client
+// for it and whether the backend is in maintenance, unavailable or failing its health check
+func (g *MCPHelper) sessionConnectionHealth(helperSessionID string) []adminConnectionHealth {
+	g.connectionsLock.RLock()
+	conn := g.clientConnections[helperSessionID]
+	connected := make(map[string]string)
+	if conn != nil {
+		for name := range conn.Clients {
+			connected[name] = conn.SessionIDs[name]
+		}
+	}
+	g.connectionsLock.RUnlock()
+
+	g.health.mu.RLock()
+	unreachable := maps.Clone(g.health.errors)
+	g.health.mu.RUnlock()
+
+	health := make([]adminConnectionHealth, 0, len(g.backends))
+	for _, backend := range g.backends {
+		session, isConnected := connected[backend.Name]
+		unavailable, _ := g.BackendUnavailable(backend.Name)
+		health = append(health, adminConnectionHealth{
+			Backend:     backend.Name,
+			Connected:   isConnected,
+			Session:     session,
+			Maintenance: g.IsBackendInMaintenance(backend.Name),
+			Unavailable: unavailable,
+			Unreachable: unreachable[backend.Name],
+		})
+	}
+	return health
+}
@@ -0,0 +1,44 @@
+package main
+
+import "sync"
+
+// singleFlight collapses concurrent calls for the same key into one execution,
+// every caller waiting on and sharing the result of the call in progress
+type singleFlight struct {
+	calls map[string]*flightCall
+	lock  sync.Mutex
+}
+
+// flightCall is an in-progress or completed call
+type flightCall struct {
+	done chan struct{}
+	err  error
+}
+
+// do runs fn for key unless a call for key is already in progress, in which case it waits for
+// that call and returns its error; shared reports whether the result came from another caller's run
+func (f *singleFlight) do(key string, fn func() error) (shared bool, err error) {
+	f.lock.Lock()
+	if f.calls == nil {
+		f.calls = make(map[string]*flightCall)
+	}
+	if call, inProgress := f.calls[key]; inProgress {
+		f.lock.Unlock()
+		<-call.done
+		return true, call.err
+	}
+
+	call := &flightCall{done: make(chan struct{})}
+	f.calls[key] = call
+	f.lock.Unlock()
+
+	defer func() {
+		f.lock.Lock()
+		delete(f.calls, key)
+		f.lock.Unlock()
+		close(call.done)
+	}()
+
+	call.err = fn()
+	return false, call.err
+}
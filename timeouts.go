@@ -0,0 +1,50 @@
+package main
+
+import (
+	"log"
+	"time"
+)
+
+// Timeouts holds the deadlines for helper-initiated operations
+type Timeouts struct {
+	Init             time.Duration // Creating and configuring a client's backend sessions
+	InitQueue        time.Duration // Waiting for a backend session-creation slot before giving up
+	Discovery        time.Duration // Startup connection and tool discovery against each backend
+	DiscoveryBudget  time.Duration // Total time for one tool aggregation or refresh across all backends
+	Shutdown         time.Duration // Draining in-flight ext-proc streams before the process exits
+	HTTPDrain        time.Duration // Draining in-flight MCP HTTP requests before the process exits
+	Keepalive        time.Duration // Interval between server keepalive pings to Envoy
+	KeepaliveTimeout time.Duration // How long to wait for a keepalive ack before dropping the connection
+	SessionTTL       time.Duration // Age after which a session's mapping and backend clients are evicted, 0 keeps them forever
+	SessionReap      time.Duration // Interval between sweeps for expired sessions
+	HealthCheck      time.Duration // Interval between backend health checks backing /readyz
+	WarmUp           time.Duration // Priming a new backend session with its configured warm-up call
+	ToolRefresh      time.Duration // Interval between runtime tool refreshes from every backend, 0 disables them
+	ToolsChanged     time.Duration // Wait after a backend's tools/list_changed before refreshing it, gathering repeats
+}
+
+// loadTimeouts reads the helper timeouts from the environment, falling back to sane defaults
+func loadTimeouts() Timeouts {
+	timeouts := Timeouts{
+		Init:             getEnvDuration("INIT_TIMEOUT", 10*time.Second),
+		InitQueue:        getEnvDuration("INIT_QUEUE_TIMEOUT", 5*time.Second),
+		Discovery:        getEnvDuration("DISCOVERY_TIMEOUT", 10*time.Second),
+		DiscoveryBudget:  getEnvDuration("DISCOVERY_BUDGET", 30*time.Second),
+		Shutdown:         getEnvDuration("SHUTDOWN_TIMEOUT", 5*time.Second),
+		HTTPDrain:        getEnvDuration("HTTP_DRAIN_TIMEOUT", 10*time.Second),
+		Keepalive:        getEnvDuration("GRPC_KEEPALIVE_TIME", 30*time.Second),
+		KeepaliveTimeout: getEnvDuration("GRPC_KEEPALIVE_TIMEOUT", 10*time.Second),
+		SessionTTL:       getEnvDuration("SESSION_TTL", 30*time.Minute),
+		SessionReap:      getEnvDuration("SESSION_REAP_INTERVAL", time.Minute),
+		HealthCheck:      getEnvDuration("HEALTH_CHECK_INTERVAL", 15*time.Second),
+		WarmUp:           getEnvDuration("WARMUP_TIMEOUT", 5*time.Second),
+		ToolRefresh:      getEnvDuration("TOOL_REFRESH_INTERVAL", 0),
+		ToolsChanged:     getEnvDuration("TOOLS_CHANGED_DEBOUNCE", 500*time.Millisecond),
+	}
+
+	log.Printf("Timeouts: init %s (queue %s), discovery %s (budget %s), shutdown %s (HTTP drain %s), keepalive %s (timeout %s), session TTL %s (reap every %s), health check %s, warm-up %s, tool refresh %s (list_changed debounce %s)",
+		timeouts.Init, timeouts.InitQueue, timeouts.Discovery, timeouts.DiscoveryBudget, timeouts.Shutdown, timeouts.HTTPDrain, timeouts.Keepalive, timeouts.KeepaliveTimeout,
+		timeouts.SessionTTL, timeouts.SessionReap, timeouts.HealthCheck, timeouts.WarmUp, timeouts.ToolRefresh, timeouts.ToolsChanged)
+
+	return timeouts
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	extProc "mcp-helper/ext-proc"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// handleToolBackend handles the helper_tool_backend tool, reporting the owning backend recorded during aggregation.
+// Tools hidden from the caller are left out, exactly as in tools/list.
+func (g *MCPHelper) handleToolBackend(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	principal := principalFromContext(ctx)
+	toolName := req.GetString("tool", "")
+
+	owners := make(map[string]string)
+	g.toolsLock.RLock()
+	for name, backend := range g.toolBackends {
+		if (toolName == "" || name == toolName) && g.IsToolVisible(name, principal) {
+			owners[name] = backend
+		}
+	}
+	g.toolsLock.RUnlock()
+
+	// The response timeout each call gets, from its annotations or BACKEND_RESPONSE_TIMEOUTS
+	timeouts := make(map[string]string)
+	for name, backend := range owners {
+		if timeout, ok := g.toolCallPolicies.ResponseTimeout(name, backend); ok {
+			timeouts[name] = timeout.String()
+		}
+	}
+
+	if toolName != "" && len(owners) == 0 {
+		return mcp.NewToolResultError(fmt.Sprintf("Unknown tool: %s", toolName)), nil
+	}
+
+	result := map[string]any{"tools": owners, "timeouts": timeouts}
+	text, err := json.Marshal(result)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode tool backends: %w", err)
+	}
+	return mcp.NewToolResultStructured(result, string(text)), nil
+}
+
+// ToolRoute returns the backend that listed an aggregated tool and its name there, from the table built
+// during aggregation rather than by matching prefixes (implements extProc.ToolRoutes interface)
+func (g *MCPHelper) ToolRoute(toolName string) (string, string, bool) {
+	g.toolsLock.RLock()
+	backend, ok := g.toolBackends[toolName]
+	g.toolsLock.RUnlock()
+	if !ok {
+		return "", toolName, false
+	}
+	return backend, strings.TrimPrefix(toolName, extProc.ToolPrefix(backend)), true
+}
+
+// setToolTimeouts replaces the timeout hints a backend's tools declare, keyed by aggregated tool name
+func (g *MCPHelper) setToolTimeouts(backend string, timeouts map[string]time.Duration) {
+	g.toolsLock.Lock()
+	g.toolTimeouts[backend] = timeouts
+	g.toolsLock.Unlock()
+}
+
+// GetToolTimeout returns the response timeout an aggregated tool's annotations ask for, so slow tools
+// aren't cut off by their backend's timeout (implements extProc.ToolTimeouts interface)
+func (g *MCPHelper) GetToolTimeout(toolName string) (time.Duration, bool) {
+	g.toolsLock.RLock()
+	defer g.toolsLock.RUnlock()
+	timeout, ok := g.toolTimeouts[g.toolBackends[toolName]][toolName]
+	return timeout, ok
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// Tools restricted to allowlisted principals "<prefixed-tool>=<principal>|<principal>,...".
+// Tools without a rule are visible to everyone.
+var toolVisibilitySpec = getEnv("TOOL_VISIBILITY", "")
+
+// toolVisibilityRules maps a prefixed tool name to the principals allowed to see and call it
+type toolVisibilityRules map[string]map[string]bool
+
+// parseToolVisibility parses visibility rules of the form "<prefixed-tool>=<principal>|<principal>,..."
+func parseToolVisibility(spec string) (toolVisibilityRules, error) {
+	visibility := make(toolVisibilityRules)
+	for _, entry := range strings.Split(spec, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		toolName, principals, ok := strings.Cut(entry, "=")
+		toolName = strings.TrimSpace(toolName)
+		if !ok || toolName == "" {
+			return nil, fmt.Errorf("invalid visibility rule %q: expected <tool>=<principal>|<principal>", entry)
+		}
+
+		allowed := make(map[string]bool)
+		for _, principal := range strings.Split(principals, "|") {
+			if principal = strings.TrimSpace(principal); principal != "" {
+				allowed[principal] = true
+			}
+		}
+		if len(allowed) == 0 {
+			return nil, fmt.Errorf("invalid visibility rule %q: no principals", entry)
+		}
+		visibility[toolName] = allowed
+	}
+	return visibility, nil
+}
+
+// IsToolVisible reports whether a principal may see and call a tool (implements extProc.ToolVisibility interface)
+func (g *MCPHelper) IsToolVisible(toolName, principal string) bool {
+	allowed, restricted := g.toolVisibility[toolName]
+	return !restricted || allowed[principal]
+}
+
+// filterVisibleTools drops tools the requesting principal isn't allowed to see from tools/list
+func (g *MCPHelper) filterVisibleTools(ctx context.Context, tools []mcp.Tool) []mcp.Tool {
+	if len(g.toolVisibility) == 0 {
+		return tools
+	}
+
+	principal := principalFromContext(ctx)
+	visible := make([]mcp.Tool, 0, len(tools))
+	for _, tool := range tools {
+		if g.IsToolVisible(tool.Name, principal) {
+			visible = append(visible, tool)
+		}
+	}
+	return visible
+}
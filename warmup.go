@@ -0,0 +1,39 @@
+package main
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// warmUpSession issues each backend's configured warm-up call on a new session's backend clients,
+// so backends that set up per-session state lazily don't make the client's first real call slow.
+// Failures are only logged - the session works either way.
+func (h *MCPHelper) warmUpSession(connections *ClientBackendConnections) {
+	for _, backend := range h.backends {
+		backendClient := connections.Clients[backend.Name]
+		if backend.WarmUp == "" || backendClient == nil {
+			continue
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), h.timeouts.WarmUp)
+		start := time.Now()
+		var err error
+		if backend.WarmUp == "tools/list" {
+			_, err = backendClient.ListTools(ctx, mcp.ListToolsRequest{})
+		} else {
+			warmUpReq := mcp.CallToolRequest{}
+			warmUpReq.Params.Name = backend.WarmUp
+			_, err = backendClient.CallTool(ctx, warmUpReq)
+		}
+		cancel()
+
+		if err != nil {
+			log.Printf("⚠️ Warm-up %s on %s for session %s failed: %v", backend.WarmUp, backend.Name, connections.ClientSessionID, err)
+			continue
+		}
+		log.Printf("🔥 Warmed up %s for session %s with %s in %s", backend.Name, connections.ClientSessionID, backend.WarmUp, time.Since(start))
+	}
+}